@@ -2,8 +2,7 @@ package common
 
 import (
 	"context"
-	"fmt"
-	"net/http"
+	"errors"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
@@ -12,27 +11,31 @@ import (
 // CreateDatabaseIfNotExists returns a DatabaseClient for the given database, creating the database if it does not exist.
 // This ensures idempotent database creation and simplifies setup for Cosmos DB resources.
 func CreateDatabaseIfNotExists(client *azcosmos.Client, props azcosmos.DatabaseProperties, opts *azcosmos.CreateDatabaseOptions) (*azcosmos.DatabaseClient, error) {
+	return CreateDatabaseIfNotExistsCtx(context.Background(), client, props, opts)
+}
+
+// CreateDatabaseIfNotExistsCtx is like CreateDatabaseIfNotExists but uses the provided context for all Cosmos DB calls.
+func CreateDatabaseIfNotExistsCtx(ctx context.Context, client *azcosmos.Client, props azcosmos.DatabaseProperties, opts *azcosmos.CreateDatabaseOptions) (*azcosmos.DatabaseClient, error) {
 	db, err := client.NewDatabase(props.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create database client: %v", err)
+		return nil, operationError("NewDatabase", props.ID, "", err)
 	}
 
-	_, err = db.Read(context.Background(), nil)
+	_, err = db.Read(ctx, nil)
 	if err != nil {
-		if cosmosdb_errors.GetError(err).Status == http.StatusNotFound {
+		if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
 			// Database doesn't exist, try to create it
-			_, err = client.CreateDatabase(context.Background(), props, opts)
+			_, err = client.CreateDatabase(ctx, props, opts)
 			if err != nil {
-				cosmosErr := cosmosdb_errors.GetError(err)
-				if cosmosErr.Status == http.StatusConflict {
+				if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrConflict) {
 					// Database was created by another process, treat as success
 					return client.NewDatabase(props.ID)
 				}
-				return nil, fmt.Errorf("failed to create database: %v", err)
+				return nil, operationError("CreateDatabase", props.ID, "", err)
 			}
 			return client.NewDatabase(props.ID)
 		}
-		return nil, fmt.Errorf("failed to read database: %v", err)
+		return nil, operationError("ReadDatabase", props.ID, "", err)
 	}
 
 	return db, nil
@@ -41,42 +44,94 @@ func CreateDatabaseIfNotExists(client *azcosmos.Client, props azcosmos.DatabaseP
 // CreateContainerIfNotExists returns a ContainerClient for the given container, creating the container if it does not exist.
 // This is useful for idempotent container setup in Cosmos DB databases.
 func CreateContainerIfNotExists(db *azcosmos.DatabaseClient, props azcosmos.ContainerProperties, opts *azcosmos.CreateContainerOptions) (*azcosmos.ContainerClient, error) {
-	//func CreateContainerIfNotExists(db *azcosmos.DatabaseClient, containerName string) (*azcosmos.ContainerClient, error) {
+	return CreateContainerIfNotExistsCtx(context.Background(), db, props, opts)
+}
+
+// CreateContainerIfNotExistsCtx is like CreateContainerIfNotExists but uses the provided context for all Cosmos DB calls.
+func CreateContainerIfNotExistsCtx(ctx context.Context, db *azcosmos.DatabaseClient, props azcosmos.ContainerProperties, opts *azcosmos.CreateContainerOptions) (*azcosmos.ContainerClient, error) {
 	container, err := db.NewContainer(props.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create container client: %v", err)
+		return nil, operationError("NewContainer", db.ID(), props.ID, err)
 	}
 
-	_, err = container.Read(context.Background(), nil)
+	_, err = container.Read(ctx, nil)
 	if err != nil {
-		if cosmosdb_errors.GetError(err).Status == http.StatusNotFound {
+		if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
 			// Container doesn't exist, try to create it
-			_, err = db.CreateContainer(context.Background(), props, opts)
+			_, err = db.CreateContainer(ctx, props, opts)
 			if err != nil {
-				cosmosErr := cosmosdb_errors.GetError(err)
-				if cosmosErr.Status == http.StatusConflict {
+				if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrConflict) {
 					// Container was created by another process, treat as success
 					return db.NewContainer(props.ID)
 				}
-				return nil, fmt.Errorf("failed to create container: %v", err)
+				return nil, operationError("CreateContainer", db.ID(), props.ID, err)
 			}
 			return db.NewContainer(props.ID)
 		}
-		return nil, fmt.Errorf("failed to read container: %v", err)
+		return nil, operationError("ReadContainer", db.ID(), props.ID, err)
 	}
 
 	return container, nil
 }
 
+// DeleteDatabaseIfExists deletes a database, including its containers and their items.
+// It succeeds if the database does not exist, which keeps teardown code idempotent.
+func DeleteDatabaseIfExists(client *azcosmos.Client, databaseID string, opts *azcosmos.DeleteDatabaseOptions) error {
+	return DeleteDatabaseIfExistsCtx(context.Background(), client, databaseID, opts)
+}
+
+// DeleteDatabaseIfExistsCtx is like DeleteDatabaseIfExists but uses the provided context for all Cosmos DB calls.
+func DeleteDatabaseIfExistsCtx(ctx context.Context, client *azcosmos.Client, databaseID string, opts *azcosmos.DeleteDatabaseOptions) error {
+	db, err := client.NewDatabase(databaseID)
+	if err != nil {
+		return operationError("NewDatabase", databaseID, "", err)
+	}
+
+	_, err = db.Delete(ctx, opts)
+	if err != nil && !errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+		return operationError("DeleteDatabase", databaseID, "", err)
+	}
+	return nil
+}
+
+// DeleteContainerIfExists deletes a container and its items.
+// It succeeds if the container does not exist, which keeps teardown code idempotent.
+func DeleteContainerIfExists(db *azcosmos.DatabaseClient, containerID string, opts *azcosmos.DeleteContainerOptions) error {
+	return DeleteContainerIfExistsCtx(context.Background(), db, containerID, opts)
+}
+
+// DeleteContainerIfExistsCtx is like DeleteContainerIfExists but uses the provided context for all Cosmos DB calls.
+func DeleteContainerIfExistsCtx(ctx context.Context, db *azcosmos.DatabaseClient, containerID string, opts *azcosmos.DeleteContainerOptions) error {
+	container, err := db.NewContainer(containerID)
+	if err != nil {
+		return operationError("NewContainer", db.ID(), containerID, err)
+	}
+
+	_, err = container.Delete(ctx, opts)
+	if err != nil && !errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+		return operationError("DeleteContainer", db.ID(), containerID, err)
+	}
+	return nil
+}
+
 // GetAllDatabases retrieves all database properties in the Cosmos DB account.
 // Use this to enumerate or inspect all databases in the account.
 func GetAllDatabases(client *azcosmos.Client) ([]azcosmos.DatabaseProperties, error) {
+	return GetAllDatabasesCtx(context.Background(), client)
+}
+
+// GetAllDatabasesCtx is like GetAllDatabases but uses the provided context for every page request.
+// Paging stops as soon as the context is cancelled.
+func GetAllDatabasesCtx(ctx context.Context, client *azcosmos.Client) ([]azcosmos.DatabaseProperties, error) {
 	pager := client.NewQueryDatabasesPager("select * from c", nil)
 	var databases []azcosmos.DatabaseProperties
 	for pager.More() {
-		page, err := pager.NextPage(context.Background())
+		if err := ctx.Err(); err != nil {
+			return nil, operationError("QueryDatabases", "", "", err)
+		}
+		page, err := pager.NextPage(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get databases: %v", err)
+			return nil, operationError("QueryDatabases", "", "", err)
 		}
 		databases = append(databases, page.Databases...)
 	}
@@ -86,14 +141,33 @@ func GetAllDatabases(client *azcosmos.Client) ([]azcosmos.DatabaseProperties, er
 // GetAllContainers retrieves all container properties in the specified database.
 // Use this to enumerate or inspect all containers in a database.
 func GetAllContainers(client *azcosmos.DatabaseClient) ([]azcosmos.ContainerProperties, error) {
+	return GetAllContainersCtx(context.Background(), client)
+}
+
+// GetAllContainersCtx is like GetAllContainers but uses the provided context for every page request.
+// Paging stops as soon as the context is cancelled.
+func GetAllContainersCtx(ctx context.Context, client *azcosmos.DatabaseClient) ([]azcosmos.ContainerProperties, error) {
 	pager := client.NewQueryContainersPager("select * from c", nil)
 	var containers []azcosmos.ContainerProperties
 	for pager.More() {
-		page, err := pager.NextPage(context.Background())
+		if err := ctx.Err(); err != nil {
+			return nil, operationError("QueryContainers", client.ID(), "", err)
+		}
+		page, err := pager.NextPage(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get containers: %v", err)
+			return nil, operationError("QueryContainers", client.ID(), "", err)
 		}
 		containers = append(containers, page.Containers...)
 	}
 	return containers, nil
 }
+
+// operationError wraps err in a cosmosdb_errors.OperationError for an operation on a database or container.
+func operationError(operation, database, container string, err error) error {
+	return cosmosdb_errors.NewOperationError(cosmosdb_errors.OperationError{
+		Operation: operation,
+		Database:  database,
+		Container: container,
+		Err:       err,
+	})
+}
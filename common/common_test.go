@@ -0,0 +1,56 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateContainerIfNotExists_OperationError(t *testing.T) {
+	client := cosmostest.NewClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-request-charge", "1.5")
+		// RBAC: the principal lacks a data action
+		w.Header().Set("x-ms-substatus", "5301")
+		cosmostest.WriteError(w, http.StatusForbidden, "Forbidden", "denied")
+	})
+	db, err := client.NewDatabase("db")
+	require.NoError(t, err)
+
+	_, err = CreateContainerIfNotExists(db, azcosmos.ContainerProperties{ID: "orders"}, nil)
+
+	var opErr *cosmosdb_errors.OperationError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "ReadContainer", opErr.Operation)
+	assert.Equal(t, "db", opErr.Database)
+	assert.Equal(t, "orders", opErr.Container)
+	assert.Equal(t, 1.5, opErr.RequestCharge)
+	assert.Equal(t, http.StatusForbidden, cosmosdb_errors.GetError(err).Status)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrUnauthorized)
+}
+
+func TestDeleteIfExists(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	db, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "db"}, nil)
+	require.NoError(t, err)
+	_, err = CreateContainerIfNotExists(db, azcosmos.ContainerProperties{ID: "orders"}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, DeleteContainerIfExists(db, "orders", nil))
+	require.NoError(t, DeleteContainerIfExists(db, "orders", nil))
+	containers, err := GetAllContainers(db)
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+
+	require.NoError(t, DeleteDatabaseIfExists(client, "db", nil))
+	require.NoError(t, DeleteDatabaseIfExists(client, "db", nil))
+	_, err = db.Read(context.Background(), nil)
+	assert.ErrorIs(t, cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound)
+	assert.Equal(t, []string{"DELETE /dbs/db/colls/orders", "DELETE /dbs/db/colls/orders", "DELETE /dbs/db", "DELETE /dbs/db"}, account.Writes()[2:])
+}
@@ -0,0 +1,312 @@
+package common
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+const defaultInventoryConcurrency = 8
+
+// InventoryOptions configures Inventory.
+type InventoryOptions struct {
+	// Concurrency is the number of databases or containers read concurrently. Defaults to 8.
+	Concurrency int
+}
+
+// InventoryReport describes the databases and containers of an account, ordered by id.
+type InventoryReport struct {
+	Databases []DatabaseInventory `json:"databases"`
+}
+
+// DatabaseInventory describes a database and its containers.
+type DatabaseInventory struct {
+	ID string `json:"id"`
+	// Throughput is nil if the database has no shared throughput. Its Mode is ThroughputServerless for a serverless account.
+	Throughput *ThroughputSummary   `json:"throughput,omitempty"`
+	Containers []ContainerInventory `json:"containers"`
+}
+
+// ContainerInventory describes a container.
+type ContainerInventory struct {
+	ID           string                          `json:"id"`
+	PartitionKey azcosmos.PartitionKeyDefinition `json:"partitionKey"`
+	Indexing     IndexingSummary                 `json:"indexing"`
+	// DefaultTTL is nil if TTL is off, and -1 if it is on without a default.
+	DefaultTTL *int32     `json:"defaultTtl,omitempty"`
+	UniqueKeys [][]string `json:"uniqueKeys,omitempty"`
+	// Throughput is nil if the container shares the throughput of its database. Its Mode is ThroughputServerless for a serverless account.
+	Throughput *ThroughputSummary `json:"throughput,omitempty"`
+	// DocumentCount is the approximate number of documents reported by the service, or nil if it was not reported.
+	DocumentCount *int64 `json:"documentCount,omitempty"`
+}
+
+// ThroughputSummary is the provisioned throughput of a database or container.
+type ThroughputSummary struct {
+	Mode ThroughputMode `json:"mode"`
+	// Throughput is the provisioned RU/s in manual mode, and the maximum RU/s in autoscale mode.
+	Throughput int32 `json:"throughput"`
+}
+
+func (s *ThroughputSummary) String() string {
+	return ThroughputInfo{Mode: s.Mode, Throughput: s.Throughput}.String()
+}
+
+// IndexingSummary summarises an indexing policy.
+type IndexingSummary struct {
+	Mode             string   `json:"mode"`
+	IncludedPaths    []string `json:"includedPaths,omitempty"`
+	ExcludedPaths    []string `json:"excludedPaths,omitempty"`
+	CompositeIndexes int      `json:"compositeIndexes,omitempty"`
+	SpatialIndexes   int      `json:"spatialIndexes,omitempty"`
+}
+
+// String returns e.g. "consistent, 1 included, 2 excluded, 1 composite".
+func (s IndexingSummary) String() string {
+	if s.Mode == "" || strings.EqualFold(s.Mode, string(azcosmos.IndexingModeNone)) {
+		return "none"
+	}
+	parts := []string{s.Mode, fmt.Sprintf("%d included", len(s.IncludedPaths)), fmt.Sprintf("%d excluded", len(s.ExcludedPaths))}
+	if s.CompositeIndexes > 0 {
+		parts = append(parts, fmt.Sprintf("%d composite", s.CompositeIndexes))
+	}
+	if s.SpatialIndexes > 0 {
+		parts = append(parts, fmt.Sprintf("%d spatial", s.SpatialIndexes))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Inventory walks all databases and containers of an account concurrently and reports their partition keys,
+// indexing policies, TTL, unique keys, throughput and approximate document counts, e.g. for audits.
+// The report can be rendered with WriteJSON, WriteMarkdown and WriteTable.
+func Inventory(client *azcosmos.Client, opts *InventoryOptions) (*InventoryReport, error) {
+	return InventoryCtx(context.Background(), client, opts)
+}
+
+// InventoryCtx is like Inventory but uses the provided context for all Cosmos DB calls.
+func InventoryCtx(ctx context.Context, client *azcosmos.Client, opts *InventoryOptions) (*InventoryReport, error) {
+	o := InventoryOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultInventoryConcurrency
+	}
+
+	databases, err := GetAllDatabasesCtx(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	report := &InventoryReport{Databases: make([]DatabaseInventory, len(databases))}
+	type containerRef struct {
+		db        *azcosmos.DatabaseClient
+		inventory *ContainerInventory
+	}
+	var (
+		mu         sync.Mutex
+		containers []containerRef
+	)
+
+	entries := make([]*DatabaseInventory, len(databases))
+	for i, props := range databases {
+		report.Databases[i].ID = props.ID
+		entries[i] = &report.Databases[i]
+	}
+	err = forEach(ctx, entries, o.Concurrency, func(ctx context.Context, inventory *DatabaseInventory) error {
+		db, err := client.NewDatabase(inventory.ID)
+		if err != nil {
+			return operationError("NewDatabase", inventory.ID, "", err)
+		}
+		if inventory.Throughput, err = throughputSummary(ctx, db); err != nil {
+			return err
+		}
+		props, err := GetAllContainersCtx(ctx, db)
+		if err != nil {
+			return err
+		}
+		inventory.Containers = make([]ContainerInventory, len(props))
+		mu.Lock()
+		defer mu.Unlock()
+		for j, p := range props {
+			inventory.Containers[j].ID = p.ID
+			containers = append(containers, containerRef{db: db, inventory: &inventory.Containers[j]})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = forEach(ctx, containers, o.Concurrency, func(ctx context.Context, ref containerRef) error {
+		return inventoryContainer(ctx, ref.db, ref.inventory)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(report.Databases, func(a, b DatabaseInventory) int { return cmp.Compare(a.ID, b.ID) })
+	for _, db := range report.Databases {
+		slices.SortFunc(db.Containers, func(a, b ContainerInventory) int { return cmp.Compare(a.ID, b.ID) })
+	}
+	return report, nil
+}
+
+// inventoryContainer fills in the inventory of the container inventory.ID of db.
+func inventoryContainer(ctx context.Context, db *azcosmos.DatabaseClient, inventory *ContainerInventory) error {
+	container, err := db.NewContainer(inventory.ID)
+	if err != nil {
+		return operationError("NewContainer", db.ID(), inventory.ID, err)
+	}
+	resp, err := container.Read(ctx, &azcosmos.ReadContainerOptions{PopulateQuotaInfo: true})
+	if err != nil {
+		return operationError("ReadContainer", db.ID(), inventory.ID, err)
+	}
+	props := resp.ContainerProperties
+	inventory.PartitionKey = props.PartitionKeyDefinition
+	inventory.Indexing = indexingSummary(props.IndexingPolicy)
+	inventory.DefaultTTL = props.DefaultTimeToLive
+	inventory.UniqueKeys = uniqueKeys(props.UniqueKeyPolicy)
+	if resp.RawResponse != nil {
+		inventory.DocumentCount = documentCount(resp.RawResponse.Header.Get("x-ms-resource-usage"))
+	}
+	inventory.Throughput, err = throughputSummary(ctx, container)
+	return err
+}
+
+// throughputSummary returns the dedicated throughput of a resource, or nil if it has none.
+func throughputSummary(ctx context.Context, resource ThroughputResource) (*ThroughputSummary, error) {
+	info, err := dedicatedThroughput(ctx, resource)
+	if info == nil || err != nil {
+		return nil, err
+	}
+	return &ThroughputSummary{Mode: info.Mode, Throughput: info.Throughput}, nil
+}
+
+func indexingSummary(policy *azcosmos.IndexingPolicy) IndexingSummary {
+	if policy == nil {
+		return IndexingSummary{}
+	}
+	summary := IndexingSummary{
+		Mode:             string(policy.IndexingMode),
+		CompositeIndexes: len(policy.CompositeIndexes),
+		SpatialIndexes:   len(policy.SpatialIndexes),
+	}
+	for _, path := range policy.IncludedPaths {
+		summary.IncludedPaths = append(summary.IncludedPaths, path.Path)
+	}
+	for _, path := range policy.ExcludedPaths {
+		summary.ExcludedPaths = append(summary.ExcludedPaths, path.Path)
+	}
+	return summary
+}
+
+// documentCount parses the documentsCount of an x-ms-resource-usage header, e.g. "documentsSize=0;documentsCount=12;...".
+func documentCount(resourceUsage string) *int64 {
+	for _, field := range strings.Split(resourceUsage, ";") {
+		if value, ok := strings.CutPrefix(field, "documentsCount="); ok {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return &n
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *InventoryReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes the report as a Markdown section per database, with a table of its containers.
+func (r *InventoryReport) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	for i, db := range r.Databases {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n", db.ID)
+		fmt.Fprintf(&b, "Throughput: %s\n\n", databaseThroughput(db))
+		if len(db.Containers) == 0 {
+			b.WriteString("No containers.\n")
+			continue
+		}
+		b.WriteString("| Container | Partition key | Indexing | TTL | Unique keys | Throughput | Documents |\n")
+		b.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, c := range db.Containers {
+			row := c.columns()
+			for j, column := range row {
+				row[j] = strings.ReplaceAll(column, "|", `\|`)
+			}
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteTable writes the report as an aligned plain text table with a row per database and container.
+func (r *InventoryReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATABASE\tCONTAINER\tPARTITION KEY\tINDEXING\tTTL\tUNIQUE KEYS\tTHROUGHPUT\tDOCUMENTS")
+	for _, db := range r.Databases {
+		fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\t%s\t-\n", db.ID, databaseThroughput(db))
+		for _, c := range db.Containers {
+			fmt.Fprintf(tw, "%s\t%s\n", db.ID, strings.Join(c.columns(), "\t"))
+		}
+	}
+	return tw.Flush()
+}
+
+func databaseThroughput(db DatabaseInventory) string {
+	if db.Throughput == nil {
+		return "none"
+	}
+	return db.Throughput.String()
+}
+
+// columns returns the rendered columns of a container: id, partition key, indexing, TTL, unique keys, throughput, documents.
+func (c ContainerInventory) columns() []string {
+	pk := strings.Join(c.PartitionKey.Paths, ", ")
+	if c.PartitionKey.Kind == azcosmos.PartitionKeyKindMultiHash {
+		pk += " (hierarchical)"
+	}
+
+	ttl := "off"
+	if c.DefaultTTL != nil {
+		ttl = "on"
+		if *c.DefaultTTL > 0 {
+			ttl = fmt.Sprintf("%ds", *c.DefaultTTL)
+		}
+	}
+
+	uniqueKeys := "-"
+	if len(c.UniqueKeys) > 0 {
+		keys := make([]string, len(c.UniqueKeys))
+		for i, paths := range c.UniqueKeys {
+			keys[i] = "(" + strings.Join(paths, ", ") + ")"
+		}
+		uniqueKeys = strings.Join(keys, " ")
+	}
+
+	throughput := "shared"
+	if c.Throughput != nil {
+		throughput = c.Throughput.String()
+	}
+
+	documents := "?"
+	if c.DocumentCount != nil {
+		documents = strconv.FormatInt(*c.DocumentCount, 10)
+	}
+	return []string{c.ID, pk, c.Indexing.String(), ttl, uniqueKeys, throughput, documents}
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInventoryAccount(t *testing.T) *azcosmos.Client {
+	t.Helper()
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	shared := azcosmos.NewManualThroughputProperties(400)
+	shop, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "shop"}, &azcosmos.CreateDatabaseOptions{ThroughputProperties: &shared})
+	require.NoError(t, err)
+	ttl := int32(3600)
+	orders, err := CreateContainerIfNotExists(shop, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Kind: azcosmos.PartitionKeyKindHash, Paths: []string{"/customerId"}},
+		DefaultTimeToLive:      &ttl,
+		UniqueKeyPolicy:        &azcosmos.UniqueKeyPolicy{UniqueKeys: []azcosmos.UniqueKey{{Paths: []string{"/orderNumber"}}}},
+		IndexingPolicy: &azcosmos.IndexingPolicy{
+			IndexingMode:     azcosmos.IndexingModeConsistent,
+			Automatic:        true,
+			IncludedPaths:    []azcosmos.IncludedPath{{Path: "/*"}},
+			ExcludedPaths:    []azcosmos.ExcludedPath{{Path: "/payload/*"}},
+			CompositeIndexes: [][]azcosmos.CompositeIndex{{{Path: "/status"}, {Path: "/createdAt"}}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	createItems(t, orders, 2)
+	autoscale := azcosmos.NewAutoscaleThroughputProperties(4000)
+	_, err = CreateContainerIfNotExists(shop, azcosmos.ContainerProperties{
+		ID:                     "events",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Kind: azcosmos.PartitionKeyKindMultiHash, Paths: []string{"/tenantId", "/userId"}, Version: 2},
+		IndexingPolicy:         &azcosmos.IndexingPolicy{IndexingMode: azcosmos.IndexingModeNone},
+	}, &azcosmos.CreateContainerOptions{ThroughputProperties: &autoscale})
+	require.NoError(t, err)
+	_, err = CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "audit"}, nil)
+	require.NoError(t, err)
+	return client
+}
+
+func TestInventory(t *testing.T) {
+	report, err := Inventory(newInventoryAccount(t), &InventoryOptions{Concurrency: 2})
+	require.NoError(t, err)
+
+	require.Len(t, report.Databases, 2)
+	audit, shop := report.Databases[0], report.Databases[1]
+	assert.Equal(t, "audit", audit.ID)
+	assert.Nil(t, audit.Throughput)
+	assert.Empty(t, audit.Containers)
+	assert.Equal(t, &ThroughputSummary{Mode: ThroughputManual, Throughput: 400}, shop.Throughput)
+
+	require.Len(t, shop.Containers, 2)
+	events, orders := shop.Containers[0], shop.Containers[1]
+	assert.Equal(t, "events", events.ID)
+	assert.Equal(t, []string{"/tenantId", "/userId"}, events.PartitionKey.Paths)
+	assert.Equal(t, &ThroughputSummary{Mode: ThroughputAutoscale, Throughput: 4000}, events.Throughput)
+	assert.Equal(t, "orders", orders.ID)
+	assert.Nil(t, orders.Throughput)
+	assert.Equal(t, int32(3600), *orders.DefaultTTL)
+	assert.Equal(t, [][]string{{"/orderNumber"}}, orders.UniqueKeys)
+	assert.Equal(t, IndexingSummary{
+		Mode:             "Consistent",
+		IncludedPaths:    []string{"/*"},
+		ExcludedPaths:    []string{"/payload/*"},
+		CompositeIndexes: 1,
+	}, orders.Indexing)
+	require.NotNil(t, orders.DocumentCount)
+	assert.Equal(t, int64(2), *orders.DocumentCount)
+}
+
+func TestInventoryReport_Write(t *testing.T) {
+	report, err := Inventory(newInventoryAccount(t), nil)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	require.NoError(t, report.WriteTable(&b))
+	assert.Equal(t, `DATABASE  CONTAINER  PARTITION KEY                      INDEXING                                         TTL    UNIQUE KEYS     THROUGHPUT               DOCUMENTS
+audit     -          -                                  -                                                -      -               none                     -
+shop      -          -                                  -                                                -      -               manual 400 RU/s          -
+shop      events     /tenantId, /userId (hierarchical)  none                                             off    -               autoscale max 4000 RU/s  0
+shop      orders     /customerId                        Consistent, 1 included, 1 excluded, 1 composite  3600s  (/orderNumber)  shared                   2
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, report.WriteMarkdown(&b))
+	assert.Equal(t, `## audit
+
+Throughput: none
+
+No containers.
+
+## shop
+
+Throughput: manual 400 RU/s
+
+| Container | Partition key | Indexing | TTL | Unique keys | Throughput | Documents |
+|---|---|---|---|---|---|---|
+| events | /tenantId, /userId (hierarchical) | none | off | - | autoscale max 4000 RU/s | 0 |
+| orders | /customerId | Consistent, 1 included, 1 excluded, 1 composite | 3600s | (/orderNumber) | shared | 2 |
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, report.WriteJSON(&b))
+	var decoded InventoryReport
+	require.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	assert.Equal(t, report, &decoded)
+}
+
+func TestDocumentCount(t *testing.T) {
+	assert.Equal(t, int64(12), *documentCount("documentsSize=3;documentsCount=12;collectionSize=5"))
+	assert.Nil(t, documentCount(""))
+	assert.Nil(t, documentCount("documentsCount=many"))
+}
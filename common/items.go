@@ -0,0 +1,327 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/operations"
+)
+
+const (
+	defaultItemConcurrency = 8
+	defaultItemPageSize    = 1000
+)
+
+// systemProperties are set by the service on every item and are dropped when items are copied.
+var systemProperties = []string{"_rid", "_self", "_etag", "_attachments", "_ts"}
+
+// TruncateOptions configures TruncateContainer.
+type TruncateOptions struct {
+	// Concurrency is the number of logical partitions whose items are deleted concurrently. Defaults to 8.
+	Concurrency int
+	// PageSize is the number of items read per query page. Defaults to 1000.
+	PageSize int32
+}
+
+// TruncateContainer deletes all items of a container, keeping the container with its settings and throughput,
+// and returns the number of deleted items. Items are read page by page, grouped by logical partition,
+// and the partitions of a page are deleted concurrently. Deleting a container and creating it again is cheaper
+// for large containers, but loses throughput and policies that are not recreated.
+// Items that are already gone when they are deleted are not counted. TruncateContainer fails if none of the items
+// of a page can be deleted, instead of querying the same items again and again.
+func TruncateContainer(container *azcosmos.ContainerClient, opts *TruncateOptions) (int, error) {
+	return TruncateContainerCtx(context.Background(), container, opts)
+}
+
+// TruncateContainerCtx is like TruncateContainer but uses the provided context for all Cosmos DB calls.
+func TruncateContainerCtx(ctx context.Context, container *azcosmos.ContainerClient, opts *TruncateOptions) (int, error) {
+	o := TruncateOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultItemConcurrency
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = defaultItemPageSize
+	}
+
+	resp, err := container.Read(ctx, nil)
+	if err != nil {
+		return 0, operationError("ReadContainer", "", container.ID(), err)
+	}
+	pkDef := resp.ContainerProperties.PartitionKeyDefinition
+	query := keysQuery(pkDef)
+
+	var deleted atomic.Int64
+	continuation := ""
+	for {
+		page, err := operations.ExecuteQueryPageCtx[json.RawMessage](ctx, container, query, azcosmos.NewPartitionKey(), o.PageSize, continuation, nil)
+		if err != nil {
+			return int(deleted.Load()), err
+		}
+		if len(page.Items) == 0 {
+			// cross-partition queries can return empty pages before the last one
+			if page.ContinuationToken == "" {
+				return int(deleted.Load()), nil
+			}
+			continuation = page.ContinuationToken
+			continue
+		}
+
+		partitions, err := groupByPartition(page.Items, pkDef)
+		if err != nil {
+			return int(deleted.Load()), err
+		}
+		var pageDeleted atomic.Int64
+		err = forEach(ctx, partitions, o.Concurrency, func(ctx context.Context, p partitionItems) error {
+			for _, item := range p.items {
+				id, _ := item["id"].(string)
+				_, err := container.DeleteItem(ctx, p.pk, id, nil)
+				if err != nil {
+					if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+						// deleted concurrently
+						continue
+					}
+					return itemError("DeleteItem", "", container.ID(), id, err)
+				}
+				pageDeleted.Add(1)
+			}
+			return nil
+		})
+		deleted.Add(pageDeleted.Load())
+		if err != nil {
+			return int(deleted.Load()), err
+		}
+		if pageDeleted.Load() == 0 {
+			// the same items would be returned again and again, e.g. if their partition keys cannot be derived exactly
+			return int(deleted.Load()), operationError("DeleteItem", "", container.ID(),
+				fmt.Errorf("none of the %d items of a page could be deleted", len(page.Items)))
+		}
+		// query again from the start: the deleted items are gone, and continuation tokens may not survive the deletes
+		continuation = ""
+	}
+}
+
+// CloneOptions configures CloneContainer.
+type CloneOptions struct {
+	// Properties, if set, modifies the properties of the new container before it is created,
+	// e.g. to change its indexing policy for a blue/green reindex.
+	Properties func(props *azcosmos.ContainerProperties)
+	// CreateOptions are passed to CreateContainer. By default, the new container gets the dedicated
+	// throughput of the source container, if it has any.
+	CreateOptions *azcosmos.CreateContainerOptions
+	// SkipItems creates the new container without copying items.
+	SkipItems bool
+	// Concurrency is the number of logical partitions whose items are written concurrently. Defaults to 8.
+	Concurrency int
+	// PageSize is the number of items read per query page. Defaults to 1000.
+	PageSize int32
+}
+
+// CloneContainer creates container targetID in db with the properties of source, as returned by source.Read,
+// and copies the items of source into it. It returns the new container and the number of copied items.
+// It fails if the target container already exists. Items written to source while it is cloned may not be copied.
+func CloneContainer(source *azcosmos.ContainerClient, db *azcosmos.DatabaseClient, targetID string, opts *CloneOptions) (*azcosmos.ContainerClient, int, error) {
+	return CloneContainerCtx(context.Background(), source, db, targetID, opts)
+}
+
+// CloneContainerCtx is like CloneContainer but uses the provided context for all Cosmos DB calls.
+func CloneContainerCtx(ctx context.Context, source *azcosmos.ContainerClient, db *azcosmos.DatabaseClient, targetID string, opts *CloneOptions) (*azcosmos.ContainerClient, int, error) {
+	o := CloneOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultItemConcurrency
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = defaultItemPageSize
+	}
+
+	resp, err := source.Read(ctx, nil)
+	if err != nil {
+		return nil, 0, operationError("ReadContainer", "", source.ID(), err)
+	}
+	props := *resp.ContainerProperties
+	props.ID = targetID
+	props.ETag = nil
+	props.SelfLink = ""
+	props.ResourceID = ""
+	props.LastModified = time.Time{}
+	if o.Properties != nil {
+		o.Properties(&props)
+	}
+
+	createOpts := o.CreateOptions
+	if createOpts == nil {
+		// the clone is created without throughput if the source has no dedicated throughput or the account is serverless
+		info, err := GetThroughputCtx(ctx, source)
+		switch {
+		case err == nil && info.Mode == ThroughputAutoscale:
+			throughput := azcosmos.NewAutoscaleThroughputProperties(info.Throughput)
+			createOpts = &azcosmos.CreateContainerOptions{ThroughputProperties: &throughput}
+		case err == nil:
+			throughput := azcosmos.NewManualThroughputProperties(info.Throughput)
+			createOpts = &azcosmos.CreateContainerOptions{ThroughputProperties: &throughput}
+		case !errors.Is(err, cosmosdb_errors.ErrNotFound) && !errors.Is(err, ErrServerless):
+			return nil, 0, err
+		}
+	}
+	if _, err := db.CreateContainer(ctx, props, createOpts); err != nil {
+		return nil, 0, operationError("CreateContainer", db.ID(), targetID, err)
+	}
+	target, err := db.NewContainer(targetID)
+	if err != nil {
+		return nil, 0, operationError("NewContainer", db.ID(), targetID, err)
+	}
+	if o.SkipItems {
+		return target, 0, nil
+	}
+
+	var copied atomic.Int64
+	for page, err := range operations.QueryPagesCtx[json.RawMessage](ctx, source, "SELECT * FROM c", azcosmos.NewPartitionKey(), &azcosmos.QueryOptions{PageSizeHint: o.PageSize}) {
+		if err != nil {
+			return target, int(copied.Load()), err
+		}
+		// the partition key of the new container may have been changed by the Properties hook
+		partitions, err := groupByPartition(page.Items, props.PartitionKeyDefinition)
+		if err != nil {
+			return target, int(copied.Load()), err
+		}
+		err = forEach(ctx, partitions, o.Concurrency, func(ctx context.Context, p partitionItems) error {
+			for _, item := range p.items {
+				id, _ := item["id"].(string)
+				for _, name := range systemProperties {
+					delete(item, name)
+				}
+				body, err := json.Marshal(item)
+				if err != nil {
+					return fmt.Errorf("item %q: %w", id, err)
+				}
+				if _, err := target.UpsertItem(ctx, p.pk, body, nil); err != nil {
+					return itemError("UpsertItem", db.ID(), targetID, id, err)
+				}
+				copied.Add(1)
+			}
+			return nil
+		})
+		if err != nil {
+			return target, int(copied.Load()), err
+		}
+	}
+	return target, int(copied.Load()), nil
+}
+
+// keysQuery returns a query for the id and the partition key values of every item. Nested partition key paths
+// are selected by their top-level property, so that the results keep the shape of the items.
+func keysQuery(pkDef azcosmos.PartitionKeyDefinition) string {
+	names := []string{"id"}
+	for _, path := range pkDef.Paths {
+		name, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		if name = strings.Trim(name, `"`); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	fields := make([]string, len(names))
+	for i, name := range names {
+		fields[i] = "c[" + strconv.Quote(name) + "]"
+	}
+	return "SELECT " + strings.Join(fields, ", ") + " FROM c"
+}
+
+// partitionItems are items of the same logical partition.
+type partitionItems struct {
+	pk    azcosmos.PartitionKey
+	items []map[string]any
+}
+
+// groupByPartition decodes items and groups them by logical partition, in order of first appearance.
+// Numbers are decoded as json.Number, so that copied items keep integers that a float64 cannot represent.
+func groupByPartition(items []json.RawMessage, pkDef azcosmos.PartitionKeyDefinition) ([]partitionItems, error) {
+	var partitions []partitionItems
+	index := map[string]int{}
+	for _, raw := range items {
+		var item map[string]any
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		if err := d.Decode(&item); err != nil {
+			return nil, err
+		}
+		pk, err := operations.PartitionKeyFromDocument(item, pkDef)
+		if err != nil {
+			return nil, fmt.Errorf("item %q: %w", item["id"], err)
+		}
+		// PartitionKey does not expose its values, but its Go syntax representation tells them apart
+		key := fmt.Sprintf("%#v", pk)
+		i, ok := index[key]
+		if !ok {
+			i = len(partitions)
+			index[key] = i
+			partitions = append(partitions, partitionItems{pk: pk})
+		}
+		partitions[i].items = append(partitions[i].items, item)
+	}
+	return partitions, nil
+}
+
+// forEach calls fn for every element, running at most concurrency calls at a time.
+// It returns the first error, and stops starting new calls once a call has failed.
+func forEach[T any](ctx context.Context, elements []T, concurrency int, fn func(ctx context.Context, element T) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, element := range elements {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, element); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// itemError wraps err in a cosmosdb_errors.OperationError for an operation on an item.
+func itemError(operation, database, container, itemID string, err error) error {
+	return cosmosdb_errors.NewOperationError(cosmosdb_errors.OperationError{
+		Operation: operation,
+		Database:  database,
+		Container: container,
+		ItemID:    itemID,
+		Err:       err,
+	})
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createItems(t *testing.T, container *azcosmos.ContainerClient, n int) {
+	t.Helper()
+	for i := range n {
+		customerID := fmt.Sprintf("c%d", i%2)
+		body, err := json.Marshal(map[string]any{"id": fmt.Sprint(i), "customerId": customerID})
+		require.NoError(t, err)
+		_, err = container.CreateItem(context.Background(), azcosmos.NewPartitionKeyString(customerID), body, nil)
+		require.NoError(t, err)
+	}
+}
+
+func TestTruncateContainer(t *testing.T) {
+	account := cosmostest.NewAccount()
+	container := newThroughputContainer(t, account, azcosmos.NewManualThroughputProperties(400))
+	createItems(t, container, 5)
+
+	deleted, err := TruncateContainer(container, &TruncateOptions{PageSize: 2, Concurrency: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 5, deleted)
+	assert.Empty(t, account.Items("db", "orders"))
+
+	// the container and its throughput are kept
+	info, err := GetThroughput(container)
+	require.NoError(t, err)
+	assert.Equal(t, int32(400), info.Throughput)
+
+	deleted, err = TruncateContainer(container, nil)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestTruncateContainer_NothingDeleted(t *testing.T) {
+	account := cosmostest.NewAccount()
+	createItems(t, newThroughputContainer(t, account, azcosmos.NewManualThroughputProperties(400)), 3)
+
+	// deletes that find no item, e.g. because the partition key derived from the item does not match the stored one
+	client := cosmostest.NewClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "item not found")
+			return
+		}
+		account.Handle(w, r)
+	})
+	db, err := client.NewDatabase("db")
+	require.NoError(t, err)
+	container, err := db.NewContainer("orders")
+	require.NoError(t, err)
+
+	deleted, err := TruncateContainer(container, nil)
+	assert.ErrorContains(t, err, "none of the 3 items of a page could be deleted")
+	assert.Zero(t, deleted)
+	assert.Len(t, account.Items("db", "orders"), 3)
+}
+
+func TestKeysQuery(t *testing.T) {
+	assert.Equal(t, `SELECT c["id"], c["customerId"] FROM c`, keysQuery(azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}}))
+	assert.Equal(t, `SELECT c["id"], c["tenant"] FROM c`, keysQuery(azcosmos.PartitionKeyDefinition{Paths: []string{"/tenant/id", "/tenant/region", "/id"}}))
+}
+
+func TestCloneContainer_LargeIntegers(t *testing.T) {
+	account := cosmostest.NewAccount()
+	source := newThroughputContainer(t, account, azcosmos.NewManualThroughputProperties(400))
+	_, err := source.CreateItem(context.Background(), azcosmos.NewPartitionKeyString("c1"),
+		[]byte(`{"id":"a","customerId":"c1","seq":9007199254740993,"price":1.10}`), nil)
+	require.NoError(t, err)
+	db, err := cosmostest.NewClient(t, account.Handle).NewDatabase("db")
+	require.NoError(t, err)
+
+	target, copied, err := CloneContainer(source, db, "orders-v2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, copied)
+	resp, err := target.ReadItem(context.Background(), azcosmos.NewPartitionKeyString("c1"), "a", nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(resp.Value), `"seq":9007199254740993`)
+	assert.Contains(t, string(resp.Value), `"price":1.10`)
+}
+
+func TestCloneContainer(t *testing.T) {
+	account := cosmostest.NewAccount()
+	source := newThroughputContainer(t, account, azcosmos.NewAutoscaleThroughputProperties(4000))
+	createItems(t, source, 3)
+	db, err := cosmostest.NewClient(t, account.Handle).NewDatabase("db")
+	require.NoError(t, err)
+
+	ttl := int32(3600)
+	target, copied, err := CloneContainer(source, db, "orders-v2", &CloneOptions{
+		Properties: func(props *azcosmos.ContainerProperties) { props.DefaultTimeToLive = &ttl },
+		PageSize:   2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, copied)
+	assert.Equal(t, "orders-v2", target.ID())
+
+	resp, err := target.Read(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/customerId"}, resp.ContainerProperties.PartitionKeyDefinition.Paths)
+	assert.Equal(t, &ttl, resp.ContainerProperties.DefaultTimeToLive)
+	info, err := GetThroughput(target)
+	require.NoError(t, err)
+	assert.Equal(t, ThroughputInfo{Mode: ThroughputAutoscale, Throughput: 4000, MinThroughput: 400}, info)
+
+	var ids []any
+	for _, item := range account.Items("db", "orders-v2") {
+		ids = append(ids, item["id"])
+	}
+	assert.ElementsMatch(t, []any{"0", "1", "2"}, ids)
+
+	// the target must not exist
+	_, _, err = CloneContainer(source, db, "orders-v2", &CloneOptions{SkipItems: true})
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrConflict)
+}
@@ -0,0 +1,179 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+// ListOptions filters ListDatabases and ListContainers. All filters that are set must match.
+type ListOptions struct {
+	// Prefix keeps databases or containers whose id starts with Prefix.
+	// Without a Query, it is evaluated by the service, so other resources are not fetched.
+	Prefix string
+	// Pattern keeps databases or containers whose id matches the regular expression. It is evaluated by the client.
+	Pattern *regexp.Regexp
+	// Query is a filter query that replaces "SELECT * FROM c", e.g. "SELECT * FROM c WHERE c.id IN (@a, @b)".
+	Query string
+	// Parameters are the parameters of Query.
+	Parameters []azcosmos.QueryParameter
+	// IncludeThroughput reads the dedicated throughput of every listed database or container, at the cost of a request each.
+	IncludeThroughput bool
+}
+
+// DatabaseEntry is a database listed by ListDatabases.
+type DatabaseEntry struct {
+	Properties azcosmos.DatabaseProperties
+	// Throughput is the shared throughput of the database. It is nil if the database has none,
+	// or if ListOptions.IncludeThroughput is not set. Its Mode is ThroughputServerless for a serverless account.
+	Throughput *ThroughputInfo
+}
+
+// ContainerEntry is a container listed by ListContainers.
+type ContainerEntry struct {
+	Properties azcosmos.ContainerProperties
+	// Throughput is the dedicated throughput of the container. It is nil if the container shares the throughput
+	// of its database, or if ListOptions.IncludeThroughput is not set. Its Mode is ThroughputServerless for a serverless account.
+	Throughput *ThroughputInfo
+}
+
+// ListDatabases returns an iterator over the databases of the account that match opts.
+// Pages are fetched lazily as the caller ranges over the results; breaking out of the loop stops fetching.
+// If a request fails, the error is yielded and iteration stops.
+func ListDatabases(client *azcosmos.Client, opts *ListOptions) iter.Seq2[DatabaseEntry, error] {
+	return ListDatabasesCtx(context.Background(), client, opts)
+}
+
+// ListDatabasesCtx is like ListDatabases but uses the provided context for all Cosmos DB calls.
+func ListDatabasesCtx(ctx context.Context, client *azcosmos.Client, opts *ListOptions) iter.Seq2[DatabaseEntry, error] {
+	return func(yield func(DatabaseEntry, error) bool) {
+		o := ListOptions{}
+		if opts != nil {
+			o = *opts
+		}
+		query, parameters := o.query()
+		pager := client.NewQueryDatabasesPager(query, &azcosmos.QueryDatabasesOptions{QueryParameters: parameters})
+		for pager.More() {
+			if err := ctx.Err(); err != nil {
+				yield(DatabaseEntry{}, operationError("QueryDatabases", "", "", err))
+				return
+			}
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				yield(DatabaseEntry{}, operationError("QueryDatabases", "", "", err))
+				return
+			}
+			for _, props := range page.Databases {
+				if !o.matches(props.ID) {
+					continue
+				}
+				entry := DatabaseEntry{Properties: props}
+				if o.IncludeThroughput {
+					db, err := client.NewDatabase(props.ID)
+					if err != nil {
+						yield(DatabaseEntry{}, operationError("NewDatabase", props.ID, "", err))
+						return
+					}
+					if entry.Throughput, err = dedicatedThroughput(ctx, db); err != nil {
+						yield(DatabaseEntry{}, err)
+						return
+					}
+				}
+				if !yield(entry, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ListContainers returns an iterator over the containers of a database that match opts.
+// Pages are fetched lazily as the caller ranges over the results; breaking out of the loop stops fetching.
+// If a request fails, the error is yielded and iteration stops.
+func ListContainers(db *azcosmos.DatabaseClient, opts *ListOptions) iter.Seq2[ContainerEntry, error] {
+	return ListContainersCtx(context.Background(), db, opts)
+}
+
+// ListContainersCtx is like ListContainers but uses the provided context for all Cosmos DB calls.
+func ListContainersCtx(ctx context.Context, db *azcosmos.DatabaseClient, opts *ListOptions) iter.Seq2[ContainerEntry, error] {
+	return func(yield func(ContainerEntry, error) bool) {
+		o := ListOptions{}
+		if opts != nil {
+			o = *opts
+		}
+		query, parameters := o.query()
+		pager := db.NewQueryContainersPager(query, &azcosmos.QueryContainersOptions{QueryParameters: parameters})
+		for pager.More() {
+			if err := ctx.Err(); err != nil {
+				yield(ContainerEntry{}, operationError("QueryContainers", db.ID(), "", err))
+				return
+			}
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				yield(ContainerEntry{}, operationError("QueryContainers", db.ID(), "", err))
+				return
+			}
+			for _, props := range page.Containers {
+				if !o.matches(props.ID) {
+					continue
+				}
+				entry := ContainerEntry{Properties: props}
+				if o.IncludeThroughput {
+					container, err := db.NewContainer(props.ID)
+					if err != nil {
+						yield(ContainerEntry{}, operationError("NewContainer", db.ID(), props.ID, err))
+						return
+					}
+					if entry.Throughput, err = dedicatedThroughput(ctx, container); err != nil {
+						yield(ContainerEntry{}, err)
+						return
+					}
+				}
+				if !yield(entry, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// query returns the query sent to the service and its parameters.
+func (o ListOptions) query() (string, []azcosmos.QueryParameter) {
+	if o.Query != "" {
+		return o.Query, o.Parameters
+	}
+	if o.Prefix != "" {
+		return "SELECT * FROM c WHERE STARTSWITH(c.id, @prefix)", []azcosmos.QueryParameter{{Name: "@prefix", Value: o.Prefix}}
+	}
+	return "SELECT * FROM c", nil
+}
+
+// matches reports whether id passes the client-side filters. The prefix is checked again
+// because it is not part of a custom Query.
+func (o ListOptions) matches(id string) bool {
+	if !strings.HasPrefix(id, o.Prefix) {
+		return false
+	}
+	return o.Pattern == nil || o.Pattern.MatchString(id)
+}
+
+// dedicatedThroughput returns the dedicated throughput of a resource, or nil if it has none.
+// Resources of serverless accounts are reported with ThroughputServerless.
+func dedicatedThroughput(ctx context.Context, resource ThroughputResource) (*ThroughputInfo, error) {
+	info, err := GetThroughputCtx(ctx, resource)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrServerless):
+			return &ThroughputInfo{Mode: ThroughputServerless}, nil
+		case errors.Is(err, cosmosdb_errors.ErrNotFound):
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listQuery is the body of a database or container query.
+type listQuery struct {
+	Query      string                    `json:"query"`
+	Parameters []azcosmos.QueryParameter `json:"parameters"`
+}
+
+// newListAccount creates databases shop-eu (with shared throughput, and containers orders, orders-archive
+// with dedicated throughput and events), shop-us and audit. It returns the queries sent for databases and containers.
+func newListAccount(t *testing.T) (*cosmostest.Account, *azcosmos.Client, *[]listQuery) {
+	t.Helper()
+	account := cosmostest.NewAccount()
+	var queries []listQuery
+	client := cosmostest.NewClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if cosmostest.IsQuery(r) && (r.URL.Path == "/dbs" || strings.HasSuffix(r.URL.Path, "/colls")) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			var query listQuery
+			require.NoError(t, json.Unmarshal(body, &query))
+			queries = append(queries, query)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		account.Handle(w, r)
+	})
+
+	shared := azcosmos.NewManualThroughputProperties(400)
+	db, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "shop-eu"}, &azcosmos.CreateDatabaseOptions{ThroughputProperties: &shared})
+	require.NoError(t, err)
+	dedicated := azcosmos.NewAutoscaleThroughputProperties(1000)
+	for _, id := range []string{"orders", "orders-archive", "events"} {
+		var opts *azcosmos.CreateContainerOptions
+		if id == "orders-archive" {
+			opts = &azcosmos.CreateContainerOptions{ThroughputProperties: &dedicated}
+		}
+		_, err = CreateContainerIfNotExists(db, azcosmos.ContainerProperties{
+			ID:                     id,
+			PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/id"}},
+		}, opts)
+		require.NoError(t, err)
+	}
+	for _, id := range []string{"shop-us", "audit"} {
+		_, err = CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: id}, nil)
+		require.NoError(t, err)
+	}
+	return account, client, &queries
+}
+
+func TestListDatabases(t *testing.T) {
+	_, client, queries := newListAccount(t)
+
+	var ids []string
+	for entry, err := range ListDatabases(client, &ListOptions{Prefix: "shop-", IncludeThroughput: true}) {
+		require.NoError(t, err)
+		ids = append(ids, entry.Properties.ID)
+		if entry.Properties.ID == "shop-eu" {
+			assert.Equal(t, &ThroughputInfo{Mode: ThroughputManual, Throughput: 400, MinThroughput: 400}, entry.Throughput)
+		} else {
+			assert.Nil(t, entry.Throughput)
+		}
+	}
+	assert.ElementsMatch(t, []string{"shop-eu", "shop-us"}, ids)
+	// the prefix is evaluated by the service
+	require.Len(t, *queries, 1)
+	assert.Equal(t, "SELECT * FROM c WHERE STARTSWITH(c.id, @prefix)", (*queries)[0].Query)
+	assert.Equal(t, []azcosmos.QueryParameter{{Name: "@prefix", Value: "shop-"}}, (*queries)[0].Parameters)
+
+	ids = nil
+	for entry, err := range ListDatabases(client, &ListOptions{Pattern: regexp.MustCompile(`-(eu|uk)$`)}) {
+		require.NoError(t, err)
+		ids = append(ids, entry.Properties.ID)
+		assert.Nil(t, entry.Throughput)
+	}
+	assert.Equal(t, []string{"shop-eu"}, ids)
+	assert.Equal(t, "SELECT * FROM c", (*queries)[1].Query)
+}
+
+func TestListContainers(t *testing.T) {
+	account, client, queries := newListAccount(t)
+	db, err := client.NewDatabase("shop-eu")
+	require.NoError(t, err)
+
+	entries := map[string]ContainerEntry{}
+	opts := &ListOptions{
+		Prefix:            "orders",
+		Query:             "SELECT * FROM c WHERE c.id != @excluded",
+		Parameters:        []azcosmos.QueryParameter{{Name: "@excluded", Value: "audit"}},
+		IncludeThroughput: true,
+	}
+	for entry, err := range ListContainers(db, opts) {
+		require.NoError(t, err)
+		entries[entry.Properties.ID] = entry
+	}
+	require.Len(t, entries, 2)
+	assert.Nil(t, entries["orders"].Throughput)
+	assert.Equal(t, ThroughputAutoscale, entries["orders-archive"].Throughput.Mode)
+	assert.Equal(t, []string{"/id"}, entries["orders"].Properties.PartitionKeyDefinition.Paths)
+	// a custom query is sent as is, and the prefix is checked by the client
+	require.Len(t, *queries, 1)
+	assert.Equal(t, opts.Query, (*queries)[0].Query)
+	assert.Equal(t, opts.Parameters, (*queries)[0].Parameters)
+
+	// breaking out of the loop stops reading throughput
+	offers := func() int {
+		return len(slices.DeleteFunc(account.Requests(), func(r string) bool { return r != "QUERY /offers" }))
+	}
+	before := offers()
+	for _, err := range ListContainers(db, &ListOptions{IncludeThroughput: true}) {
+		require.NoError(t, err)
+		break
+	}
+	assert.Equal(t, before+1, offers())
+}
+
+func TestListContainers_Error(t *testing.T) {
+	client := cosmostest.NewClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "database not found")
+	})
+	db, err := client.NewDatabase("missing")
+	require.NoError(t, err)
+
+	calls := 0
+	for _, err := range ListContainers(db, nil) {
+		calls++
+		assert.ErrorContains(t, err, "QueryContainers")
+	}
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,291 @@
+package common
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+// ErrImmutableProperty is returned by ReconcileContainer when the desired properties change a property
+// that cannot be changed on an existing container, such as the partition key.
+var ErrImmutableProperty = errors.New("immutable container property changed")
+
+// etagExcludedPath is excluded from indexing by the service in every indexing policy.
+const etagExcludedPath = `/"_etag"/?`
+
+// PropertyChange describes a container property that differs from the desired value.
+// Current and Desired are display values, e.g. "3600" or the JSON of an indexing policy.
+type PropertyChange struct {
+	// Property is the JSON name of the property, e.g. "defaultTtl" or "indexingPolicy".
+	Property string
+	Current  string
+	Desired  string
+	// Immutable is set for properties that can only be set when the container is created.
+	Immutable bool
+}
+
+func (c PropertyChange) String() string {
+	s := fmt.Sprintf("%s: %s -> %s", c.Property, c.Current, c.Desired)
+	if c.Immutable {
+		s += " (immutable)"
+	}
+	return s
+}
+
+// ContainerDiff describes how an existing container differs from the desired properties.
+type ContainerDiff struct {
+	ID string
+	// Missing is set if the container does not exist.
+	Missing bool
+	Changes []PropertyChange
+}
+
+// Empty reports whether the container exists and matches the desired properties.
+func (d ContainerDiff) Empty() bool {
+	return !d.Missing && len(d.Changes) == 0
+}
+
+// Immutable returns the changes that cannot be applied to the existing container.
+func (d ContainerDiff) Immutable() []PropertyChange {
+	var changes []PropertyChange
+	for _, change := range d.Changes {
+		if change.Immutable {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// String renders the diff with one line per changed property.
+func (d ContainerDiff) String() string {
+	if d.Missing {
+		return fmt.Sprintf("container %q does not exist", d.ID)
+	}
+	if len(d.Changes) == 0 {
+		return fmt.Sprintf("container %q is up to date", d.ID)
+	}
+	lines := []string{fmt.Sprintf("container %q:", d.ID)}
+	for _, change := range d.Changes {
+		lines = append(lines, "  "+change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffContainerProperties compares the properties of an existing container with the desired properties.
+// Properties that are nil in desired and have a service-side default (indexing policy, analytical store TTL,
+// conflict resolution policy) are not compared, while a nil DefaultTimeToLive means that TTL is off.
+// Indexing policies are compared after normalizing the defaults the service adds, such as the "/*" included path.
+//
+// The partition key, unique keys and conflict resolution policy can only be set when a container is created,
+// so changes to them are marked as immutable.
+func DiffContainerProperties(current, desired azcosmos.ContainerProperties) ContainerDiff {
+	diff := ContainerDiff{ID: desired.ID}
+	add := func(property string, current, desired any, immutable bool) {
+		diff.Changes = append(diff.Changes, PropertyChange{
+			Property:  property,
+			Current:   display(current),
+			Desired:   display(desired),
+			Immutable: immutable,
+		})
+	}
+
+	version := desired.PartitionKeyDefinition.Version
+	currentPK, desiredPK := normalizePartitionKey(current.PartitionKeyDefinition, version), normalizePartitionKey(desired.PartitionKeyDefinition, version)
+	if !reflect.DeepEqual(currentPK, desiredPK) {
+		add("partitionKey", currentPK, desiredPK, true)
+	}
+	currentKeys, desiredKeys := uniqueKeys(current.UniqueKeyPolicy), uniqueKeys(desired.UniqueKeyPolicy)
+	if !reflect.DeepEqual(currentKeys, desiredKeys) {
+		add("uniqueKeyPolicy", currentKeys, desiredKeys, true)
+	}
+	if desired.ConflictResolutionPolicy != nil {
+		currentPolicy, desiredPolicy := normalizeConflictResolution(current.ConflictResolutionPolicy), normalizeConflictResolution(desired.ConflictResolutionPolicy)
+		if !reflect.DeepEqual(currentPolicy, desiredPolicy) {
+			add("conflictResolutionPolicy", currentPolicy, desiredPolicy, true)
+		}
+	}
+	if !reflect.DeepEqual(current.DefaultTimeToLive, desired.DefaultTimeToLive) {
+		add("defaultTtl", current.DefaultTimeToLive, desired.DefaultTimeToLive, false)
+	}
+	if desired.AnalyticalStoreTimeToLiveInSeconds != nil && !reflect.DeepEqual(current.AnalyticalStoreTimeToLiveInSeconds, desired.AnalyticalStoreTimeToLiveInSeconds) {
+		add("analyticalStorageTtl", current.AnalyticalStoreTimeToLiveInSeconds, desired.AnalyticalStoreTimeToLiveInSeconds, false)
+	}
+	if desired.IndexingPolicy != nil {
+		currentPolicy, desiredPolicy := normalizeIndexingPolicy(current.IndexingPolicy), normalizeIndexingPolicy(desired.IndexingPolicy)
+		if !reflect.DeepEqual(currentPolicy, desiredPolicy) {
+			add("indexingPolicy", currentPolicy, desiredPolicy, false)
+		}
+	}
+	return diff
+}
+
+// ReconcileOptions configures ReconcileContainer.
+type ReconcileOptions struct {
+	// DryRun computes the diff without creating or replacing the container.
+	DryRun bool
+	// CreateOptions is passed to CreateContainer if the container does not exist.
+	CreateOptions *azcosmos.CreateContainerOptions
+}
+
+// ReconcileContainer converges a container to the given properties. Unlike CreateContainerIfNotExists,
+// it compares the properties of an existing container with props (see DiffContainerProperties) and replaces the container
+// if they differ. A missing container is created.
+//
+// The returned diff describes what differed before the call. If it contains immutable changes, such as a different
+// partition key, nothing is changed and the error wraps ErrImmutableProperty.
+// A changed indexing policy is applied by the service in the background.
+func ReconcileContainer(db *azcosmos.DatabaseClient, props azcosmos.ContainerProperties, opts *ReconcileOptions) (*azcosmos.ContainerClient, ContainerDiff, error) {
+	return ReconcileContainerCtx(context.Background(), db, props, opts)
+}
+
+// ReconcileContainerCtx is like ReconcileContainer but uses the provided context for all Cosmos DB calls.
+func ReconcileContainerCtx(ctx context.Context, db *azcosmos.DatabaseClient, props azcosmos.ContainerProperties, opts *ReconcileOptions) (*azcosmos.ContainerClient, ContainerDiff, error) {
+	o := ReconcileOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	container, err := db.NewContainer(props.ID)
+	if err != nil {
+		return nil, ContainerDiff{}, operationError("NewContainer", db.ID(), props.ID, err)
+	}
+
+	resp, err := container.Read(ctx, nil)
+	if err != nil {
+		if !errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+			return nil, ContainerDiff{}, operationError("ReadContainer", db.ID(), props.ID, err)
+		}
+		diff := ContainerDiff{ID: props.ID, Missing: true}
+		if o.DryRun {
+			return container, diff, nil
+		}
+		container, err = CreateContainerIfNotExistsCtx(ctx, db, props, o.CreateOptions)
+		return container, diff, err
+	}
+
+	current := *resp.ContainerProperties
+	diff := DiffContainerProperties(current, props)
+	if immutable := diff.Immutable(); len(immutable) > 0 {
+		changes := make([]string, len(immutable))
+		for i, change := range immutable {
+			changes[i] = change.String()
+		}
+		return nil, diff, fmt.Errorf("container %q: %w, the container must be recreated: %s", props.ID, ErrImmutableProperty, strings.Join(changes, "; "))
+	}
+	if len(diff.Changes) == 0 || o.DryRun {
+		return container, diff, nil
+	}
+
+	// start from the current properties so that properties without a desired value keep their value
+	current.DefaultTimeToLive = props.DefaultTimeToLive
+	current.AnalyticalStoreTimeToLiveInSeconds = cmp.Or(props.AnalyticalStoreTimeToLiveInSeconds, current.AnalyticalStoreTimeToLiveInSeconds)
+	current.IndexingPolicy = cmp.Or(props.IndexingPolicy, current.IndexingPolicy)
+	if _, err := container.Replace(ctx, current, nil); err != nil {
+		return nil, diff, operationError("ReplaceContainer", db.ID(), props.ID, err)
+	}
+	return container, diff, nil
+}
+
+// normalizePartitionKey infers the kind like the SDK does when marshalling the definition.
+// The version is only compared if the desired definition sets one.
+func normalizePartitionKey(pk azcosmos.PartitionKeyDefinition, desiredVersion int) azcosmos.PartitionKeyDefinition {
+	if pk.Kind == "" {
+		pk.Kind = azcosmos.PartitionKeyKindHash
+		if len(pk.Paths) > 1 {
+			pk.Kind = azcosmos.PartitionKeyKindMultiHash
+		}
+	}
+	if desiredVersion == 0 {
+		pk.Version = 0
+	}
+	return pk
+}
+
+// normalizeConflictResolution applies the default resolution path of last-writer-wins, the _ts property.
+func normalizeConflictResolution(policy *azcosmos.ConflictResolutionPolicy) *azcosmos.ConflictResolutionPolicy {
+	if policy == nil {
+		return nil
+	}
+	p := *policy
+	if p.Mode == azcosmos.ConflictResolutionModeLastWriteWins && p.ResolutionPath == "" {
+		p.ResolutionPath = "/_ts"
+	}
+	return &p
+}
+
+// uniqueKeys returns the paths of each unique key, sorted so that the order of keys and paths does not matter.
+func uniqueKeys(policy *azcosmos.UniqueKeyPolicy) [][]string {
+	if policy == nil {
+		return nil
+	}
+	var keys [][]string
+	for _, key := range policy.UniqueKeys {
+		keys = append(keys, slices.Sorted(slices.Values(key.Paths)))
+	}
+	slices.SortFunc(keys, func(a, b []string) int { return slices.Compare(a, b) })
+	return keys
+}
+
+// normalizeIndexingPolicy applies the defaults of the service and sorts paths, so that equal policies compare equal.
+func normalizeIndexingPolicy(policy *azcosmos.IndexingPolicy) azcosmos.IndexingPolicy {
+	p := azcosmos.IndexingPolicy{Automatic: true, IndexingMode: azcosmos.IndexingModeConsistent}
+	if policy != nil {
+		p = *policy
+	}
+	p.IndexingMode = azcosmos.IndexingMode(strings.ToLower(string(cmp.Or(p.IndexingMode, azcosmos.IndexingModeConsistent))))
+	if p.IndexingMode == "none" {
+		return azcosmos.IndexingPolicy{IndexingMode: p.IndexingMode}
+	}
+	p.Automatic = true
+
+	p.IncludedPaths = slices.Clone(p.IncludedPaths)
+	if len(p.IncludedPaths) == 0 {
+		p.IncludedPaths = []azcosmos.IncludedPath{{Path: "/*"}}
+	}
+	slices.SortFunc(p.IncludedPaths, func(a, b azcosmos.IncludedPath) int { return strings.Compare(a.Path, b.Path) })
+
+	p.ExcludedPaths = slices.DeleteFunc(slices.Clone(p.ExcludedPaths), func(path azcosmos.ExcludedPath) bool {
+		return path.Path == etagExcludedPath
+	})
+	slices.SortFunc(p.ExcludedPaths, func(a, b azcosmos.ExcludedPath) int { return strings.Compare(a.Path, b.Path) })
+	if len(p.ExcludedPaths) == 0 {
+		p.ExcludedPaths = nil
+	}
+
+	p.SpatialIndexes = slices.Clone(p.SpatialIndexes)
+	slices.SortFunc(p.SpatialIndexes, func(a, b azcosmos.SpatialIndex) int { return strings.Compare(a.Path, b.Path) })
+	if len(p.SpatialIndexes) == 0 {
+		p.SpatialIndexes = nil
+	}
+
+	var composites [][]azcosmos.CompositeIndex
+	for _, composite := range p.CompositeIndexes {
+		composite = slices.Clone(composite)
+		for i := range composite {
+			composite[i].Order = azcosmos.CompositeIndexOrder(strings.ToLower(string(cmp.Or(composite[i].Order, azcosmos.CompositeIndexAscending))))
+		}
+		composites = append(composites, composite)
+	}
+	p.CompositeIndexes = composites
+	return p
+}
+
+// display renders a property value for a PropertyChange.
+func display(v any) string {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Slice) && rv.IsNil() {
+		return "none"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
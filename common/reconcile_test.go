@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func TestDiffContainerProperties_Normalized(t *testing.T) {
+	// as returned by the service for a container created with only a partition key and unique key
+	current := azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Kind: azcosmos.PartitionKeyKindHash, Paths: []string{"/customerId"}, Version: 2},
+		IndexingPolicy: &azcosmos.IndexingPolicy{
+			Automatic:     true,
+			IndexingMode:  "consistent",
+			IncludedPaths: []azcosmos.IncludedPath{{Path: "/*"}},
+			ExcludedPaths: []azcosmos.ExcludedPath{{Path: `/"_etag"/?`}},
+		},
+		UniqueKeyPolicy:          &azcosmos.UniqueKeyPolicy{UniqueKeys: []azcosmos.UniqueKey{{Paths: []string{"/b", "/a"}}}},
+		ConflictResolutionPolicy: &azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeLastWriteWins, ResolutionPath: "/_ts"},
+	}
+	desired := azcosmos.ContainerProperties{
+		ID:                       "orders",
+		PartitionKeyDefinition:   azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+		IndexingPolicy:           &azcosmos.IndexingPolicy{IndexingMode: azcosmos.IndexingModeConsistent},
+		UniqueKeyPolicy:          &azcosmos.UniqueKeyPolicy{UniqueKeys: []azcosmos.UniqueKey{{Paths: []string{"/a", "/b"}}}},
+		ConflictResolutionPolicy: &azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeLastWriteWins},
+	}
+
+	diff := DiffContainerProperties(current, desired)
+	assert.True(t, diff.Empty(), diff.String())
+}
+
+func TestReconcileContainer(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	db, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "db"}, nil)
+	require.NoError(t, err)
+
+	props := azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+	}
+	_, diff, err := ReconcileContainer(db, props, nil)
+	require.NoError(t, err)
+	assert.True(t, diff.Missing)
+
+	props.DefaultTimeToLive = int32Ptr(3600)
+	props.IndexingPolicy = &azcosmos.IndexingPolicy{ExcludedPaths: []azcosmos.ExcludedPath{{Path: "/payload/*"}}}
+
+	// a dry run reports the diff without replacing the container
+	writes := len(account.Writes())
+	_, diff, err = ReconcileContainer(db, props, &ReconcileOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Len(t, account.Writes(), writes)
+	assert.Equal(t, `container "orders":
+  defaultTtl: none -> 3600
+  indexingPolicy: {"automatic":true,"indexingMode":"consistent","includedPaths":[{"path":"/*"}]} -> {"automatic":true,"indexingMode":"consistent","includedPaths":[{"path":"/*"}],"excludedPaths":[{"path":"/payload/*"}]}`, diff.String())
+
+	container, diff, err := ReconcileContainer(db, props, nil)
+	require.NoError(t, err)
+	assert.Len(t, diff.Changes, 2)
+	resp, err := container.Read(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3600), *resp.ContainerProperties.DefaultTimeToLive)
+	assert.Equal(t, []azcosmos.ExcludedPath{{Path: "/payload/*"}}, resp.ContainerProperties.IndexingPolicy.ExcludedPaths)
+
+	_, diff, err = ReconcileContainer(db, props, nil)
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+}
+
+func TestReconcileContainer_Immutable(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	db, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "db"}, nil)
+	require.NoError(t, err)
+	_, err = CreateContainerIfNotExists(db, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+	}, nil)
+	require.NoError(t, err)
+
+	writes := len(account.Writes())
+	_, diff, err := ReconcileContainer(db, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/tenantId"}},
+		DefaultTimeToLive:      int32Ptr(-1),
+	}, nil)
+	require.ErrorIs(t, err, ErrImmutableProperty)
+	assert.EqualError(t, err, `container "orders": immutable container property changed, the container must be recreated: partitionKey: {"kind":"Hash","paths":["/customerId"]} -> {"kind":"Hash","paths":["/tenantId"]} (immutable)`)
+	assert.Len(t, diff.Changes, 2)
+	assert.Len(t, account.Writes(), writes)
+}
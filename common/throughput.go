@@ -0,0 +1,264 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+const defaultPollInterval = 5 * time.Second
+
+// ErrServerless is returned when reading or changing the throughput of a database or container of a serverless account,
+// which has no provisioned throughput.
+var ErrServerless = errors.New("serverless accounts have no provisioned throughput")
+
+// ErrThroughputOutOfRange is returned when a throughput change is refused by the Min and Max guards of ScaleOptions,
+// or is below the minimum throughput reported by the service.
+var ErrThroughputOutOfRange = errors.New("throughput out of range")
+
+// ThroughputMode is the provisioning mode of throughput.
+type ThroughputMode string
+
+const (
+	ThroughputManual    ThroughputMode = "manual"
+	ThroughputAutoscale ThroughputMode = "autoscale"
+	// ThroughputServerless is reported for the databases and containers of serverless accounts, which are billed
+	// per request instead of provisioned throughput.
+	ThroughputServerless ThroughputMode = "serverless"
+)
+
+// ThroughputResource is a database or container with provisioned throughput.
+// *azcosmos.DatabaseClient and *azcosmos.ContainerClient implement it.
+type ThroughputResource interface {
+	ID() string
+	ReadThroughput(ctx context.Context, o *azcosmos.ThroughputOptions) (azcosmos.ThroughputResponse, error)
+	ReplaceThroughput(ctx context.Context, throughputProperties azcosmos.ThroughputProperties, o *azcosmos.ThroughputOptions) (azcosmos.ThroughputResponse, error)
+}
+
+// ThroughputInfo describes the throughput of a database or container.
+type ThroughputInfo struct {
+	Mode ThroughputMode
+	// Throughput is the provisioned RU/s in manual mode, and the maximum RU/s in autoscale mode.
+	Throughput int32
+	// MinThroughput is the lowest value Throughput can currently be set to, as reported by the service.
+	MinThroughput int32
+	// ReplacePending is set while the service is still applying a throughput change, e.g. while partitions are split.
+	ReplacePending bool
+}
+
+func (i ThroughputInfo) String() string {
+	switch i.Mode {
+	case ThroughputAutoscale:
+		return fmt.Sprintf("autoscale max %d RU/s", i.Throughput)
+	case ThroughputServerless:
+		return "serverless"
+	}
+	return fmt.Sprintf("manual %d RU/s", i.Throughput)
+}
+
+// MigrateFunc migrates the throughput of a resource to another mode. The Cosmos DB data plane API used by
+// the Go SDK cannot switch between manual and autoscale throughput, so a MigrateFunc typically calls
+// the migrate operations of Azure Resource Manager (e.g. MigrateSQLContainerToAutoscale of the armcosmos package).
+type MigrateFunc func(ctx context.Context, resource ThroughputResource, to ThroughputMode) error
+
+// ScaleOptions configures ScaleThroughput, SwitchToManualThroughput and SwitchToAutoscaleThroughput.
+type ScaleOptions struct {
+	// Min and Max guard the target throughput: targets outside the range are refused with ErrThroughputOutOfRange.
+	// Zero means no guard.
+	Min, Max int32
+	// Wait waits until the service has applied the change. See WaitForThroughputReplace.
+	Wait bool
+	// PollInterval is the interval between reads while waiting. Defaults to 5s.
+	PollInterval time.Duration
+	// Migrate switches the throughput mode. It is required to switch between manual and autoscale throughput.
+	Migrate MigrateFunc
+}
+
+// GetThroughput returns the throughput of a database or container.
+// It fails with an error matching cosmosdb_errors.ErrNotFound if the resource has no dedicated throughput,
+// e.g. for a container that shares the throughput of its database, and with an error matching ErrServerless
+// for a database or container of a serverless account.
+func GetThroughput(resource ThroughputResource) (ThroughputInfo, error) {
+	return GetThroughputCtx(context.Background(), resource)
+}
+
+// GetThroughputCtx is like GetThroughput but uses the provided context for all Cosmos DB calls.
+func GetThroughputCtx(ctx context.Context, resource ThroughputResource) (ThroughputInfo, error) {
+	resp, err := resource.ReadThroughput(ctx, nil)
+	if err != nil {
+		if isServerlessError(err) {
+			err = fmt.Errorf("%w: %w", ErrServerless, err)
+		}
+		return ThroughputInfo{}, throughputError("ReadThroughput", resource, err)
+	}
+	return throughputInfo(resp), nil
+}
+
+// ScaleThroughput sets the throughput of a database or container to target RU/s, keeping its mode:
+// in autoscale mode, target is the maximum throughput. It returns the resulting throughput,
+// and does nothing if the throughput already is target.
+func ScaleThroughput(resource ThroughputResource, target int32, opts *ScaleOptions) (ThroughputInfo, error) {
+	return ScaleThroughputCtx(context.Background(), resource, target, opts)
+}
+
+// ScaleThroughputCtx is like ScaleThroughput but uses the provided context for all Cosmos DB calls.
+func ScaleThroughputCtx(ctx context.Context, resource ThroughputResource, target int32, opts *ScaleOptions) (ThroughputInfo, error) {
+	info, err := GetThroughputCtx(ctx, resource)
+	if err != nil {
+		return ThroughputInfo{}, err
+	}
+	return setThroughput(ctx, resource, info, info.Mode, target, opts)
+}
+
+// SwitchToManualThroughput sets manual throughput of target RU/s on a database or container,
+// migrating it from autoscale throughput with ScaleOptions.Migrate if needed.
+func SwitchToManualThroughput(resource ThroughputResource, target int32, opts *ScaleOptions) (ThroughputInfo, error) {
+	return SwitchToManualThroughputCtx(context.Background(), resource, target, opts)
+}
+
+// SwitchToManualThroughputCtx is like SwitchToManualThroughput but uses the provided context for all Cosmos DB calls.
+func SwitchToManualThroughputCtx(ctx context.Context, resource ThroughputResource, target int32, opts *ScaleOptions) (ThroughputInfo, error) {
+	info, err := GetThroughputCtx(ctx, resource)
+	if err != nil {
+		return ThroughputInfo{}, err
+	}
+	return setThroughput(ctx, resource, info, ThroughputManual, target, opts)
+}
+
+// SwitchToAutoscaleThroughput sets autoscale throughput with a maximum of target RU/s on a database or container,
+// migrating it from manual throughput with ScaleOptions.Migrate if needed.
+func SwitchToAutoscaleThroughput(resource ThroughputResource, target int32, opts *ScaleOptions) (ThroughputInfo, error) {
+	return SwitchToAutoscaleThroughputCtx(context.Background(), resource, target, opts)
+}
+
+// SwitchToAutoscaleThroughputCtx is like SwitchToAutoscaleThroughput but uses the provided context for all Cosmos DB calls.
+func SwitchToAutoscaleThroughputCtx(ctx context.Context, resource ThroughputResource, target int32, opts *ScaleOptions) (ThroughputInfo, error) {
+	info, err := GetThroughputCtx(ctx, resource)
+	if err != nil {
+		return ThroughputInfo{}, err
+	}
+	return setThroughput(ctx, resource, info, ThroughputAutoscale, target, opts)
+}
+
+// WaitForThroughputReplace waits until a pending throughput change of a database or container has been applied,
+// reading its throughput every pollInterval (5s if zero). Scaling beyond the RU/s the current partitions
+// can serve splits partitions, which can take hours; use WaitForThroughputReplaceCtx with a deadline to bound the wait.
+func WaitForThroughputReplace(resource ThroughputResource, pollInterval time.Duration) (ThroughputInfo, error) {
+	return WaitForThroughputReplaceCtx(context.Background(), resource, pollInterval)
+}
+
+// WaitForThroughputReplaceCtx is like WaitForThroughputReplace but uses the provided context for all Cosmos DB calls,
+// and stops waiting when it is cancelled.
+func WaitForThroughputReplaceCtx(ctx context.Context, resource ThroughputResource, pollInterval time.Duration) (ThroughputInfo, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	for {
+		info, err := GetThroughputCtx(ctx, resource)
+		if err != nil || !info.ReplacePending {
+			return info, err
+		}
+		select {
+		case <-ctx.Done():
+			return info, throughputError("ReadThroughput", resource, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// setThroughput changes the throughput of a resource whose current throughput is info to target RU/s in the given mode.
+func setThroughput(ctx context.Context, resource ThroughputResource, info ThroughputInfo, mode ThroughputMode, target int32, opts *ScaleOptions) (ThroughputInfo, error) {
+	o := ScaleOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
+	}
+
+	if o.Min > 0 && target < o.Min {
+		return info, fmt.Errorf("%s %q: %w: %d RU/s is below the guard of %d RU/s", resourceKind(resource), resource.ID(), ErrThroughputOutOfRange, target, o.Min)
+	}
+	if o.Max > 0 && target > o.Max {
+		return info, fmt.Errorf("%s %q: %w: %d RU/s is above the guard of %d RU/s", resourceKind(resource), resource.ID(), ErrThroughputOutOfRange, target, o.Max)
+	}
+
+	if info.Mode != mode {
+		if o.Migrate == nil {
+			return info, fmt.Errorf("%s %q has %s throughput; switching to %s throughput requires ScaleOptions.Migrate", resourceKind(resource), resource.ID(), info.Mode, mode)
+		}
+		if err := o.Migrate(ctx, resource, mode); err != nil {
+			return info, fmt.Errorf("migrate %s %q to %s throughput: %w", resourceKind(resource), resource.ID(), mode, err)
+		}
+		var err error
+		if info, err = WaitForThroughputReplaceCtx(ctx, resource, o.PollInterval); err != nil {
+			return info, err
+		}
+		if info.Mode != mode {
+			return info, fmt.Errorf("%s %q still has %s throughput after migrating to %s throughput", resourceKind(resource), resource.ID(), info.Mode, mode)
+		}
+	}
+
+	if info.Throughput == target {
+		return info, nil
+	}
+	if info.MinThroughput > 0 && target < info.MinThroughput {
+		return info, fmt.Errorf("%s %q: %w: %d RU/s is below the minimum of %d RU/s", resourceKind(resource), resource.ID(), ErrThroughputOutOfRange, target, info.MinThroughput)
+	}
+
+	props := azcosmos.NewManualThroughputProperties(target)
+	if mode == ThroughputAutoscale {
+		props = azcosmos.NewAutoscaleThroughputProperties(target)
+	}
+	resp, err := resource.ReplaceThroughput(ctx, props, nil)
+	if err != nil {
+		return info, throughputError("ReplaceThroughput", resource, err)
+	}
+	info = throughputInfo(resp)
+	if o.Wait && info.ReplacePending {
+		return WaitForThroughputReplaceCtx(ctx, resource, o.PollInterval)
+	}
+	return info, nil
+}
+
+func throughputInfo(resp azcosmos.ThroughputResponse) ThroughputInfo {
+	info := ThroughputInfo{Mode: ThroughputManual, ReplacePending: resp.IsReplacePending}
+	// autoscale offers also report the current throughput as manual throughput
+	if maxThroughput, ok := resp.ThroughputProperties.AutoscaleMaxThroughput(); ok {
+		info.Mode = ThroughputAutoscale
+		info.Throughput = maxThroughput
+	} else {
+		info.Throughput, _ = resp.ThroughputProperties.ManualThroughput()
+	}
+	if resp.MinThroughput != nil {
+		info.MinThroughput = *resp.MinThroughput
+	}
+	return info
+}
+
+func resourceKind(resource ThroughputResource) string {
+	if _, ok := resource.(*azcosmos.DatabaseClient); ok {
+		return "database"
+	}
+	return "container"
+}
+
+// isServerlessError reports whether err is the 400 BadRequest returned for offers of a serverless account.
+func isServerlessError(err error) bool {
+	cosmosErr := cosmosdb_errors.GetError(err)
+	return cosmosErr.Status == http.StatusBadRequest && strings.Contains(strings.ToLower(cosmosErr.ServiceMessage), "serverless")
+}
+
+// throughputError wraps err in a cosmosdb_errors.OperationError for a throughput operation on resource.
+func throughputError(operation string, resource ThroughputResource, err error) error {
+	if resourceKind(resource) == "database" {
+		return operationError(operation, resource.ID(), "", err)
+	}
+	return operationError(operation, "", resource.ID(), err)
+}
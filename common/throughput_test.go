@@ -0,0 +1,149 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newThroughputContainer(t *testing.T, account *cosmostest.Account, throughput azcosmos.ThroughputProperties) *azcosmos.ContainerClient {
+	t.Helper()
+	client := cosmostest.NewClient(t, account.Handle)
+	db, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "db"}, nil)
+	require.NoError(t, err)
+	container, err := CreateContainerIfNotExists(db, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+	}, &azcosmos.CreateContainerOptions{ThroughputProperties: &throughput})
+	require.NoError(t, err)
+	return container
+}
+
+func TestScaleThroughput(t *testing.T) {
+	account := cosmostest.NewAccount()
+	container := newThroughputContainer(t, account, azcosmos.NewManualThroughputProperties(400))
+
+	info, err := GetThroughput(container)
+	require.NoError(t, err)
+	assert.Equal(t, ThroughputInfo{Mode: ThroughputManual, Throughput: 400, MinThroughput: 400}, info)
+
+	info, err = ScaleThroughput(container, 1000, &ScaleOptions{Max: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1000), info.Throughput)
+
+	_, err = ScaleThroughput(container, 2000, &ScaleOptions{Max: 1000})
+	require.ErrorIs(t, err, ErrThroughputOutOfRange)
+	assert.EqualError(t, err, `container "orders": throughput out of range: 2000 RU/s is above the guard of 1000 RU/s`)
+
+	_, err = ScaleThroughput(container, 300, nil)
+	assert.ErrorIs(t, err, ErrThroughputOutOfRange)
+
+	// no write if the throughput is unchanged
+	writes := len(account.Writes())
+	_, err = ScaleThroughput(container, 1000, nil)
+	require.NoError(t, err)
+	assert.Len(t, account.Writes(), writes)
+}
+
+func TestScaleThroughput_Wait(t *testing.T) {
+	account := cosmostest.NewAccount()
+	container := newThroughputContainer(t, account, azcosmos.NewAutoscaleThroughputProperties(4000))
+
+	account.SetReplacePending(3)
+	info, err := ScaleThroughput(container, 20000, &ScaleOptions{Wait: true, PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, ThroughputInfo{Mode: ThroughputAutoscale, Throughput: 20000, MinThroughput: 400}, info)
+
+	account.SetReplacePending(100)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = WaitForThroughputReplaceCtx(ctx, container, time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSwitchToAutoscaleThroughput(t *testing.T) {
+	account := cosmostest.NewAccount()
+	container := newThroughputContainer(t, account, azcosmos.NewManualThroughputProperties(400))
+
+	_, err := SwitchToAutoscaleThroughput(container, 4000, nil)
+	assert.EqualError(t, err, `container "orders" has manual throughput; switching to autoscale throughput requires ScaleOptions.Migrate`)
+
+	var migrated []ThroughputMode
+	migrate := func(ctx context.Context, resource ThroughputResource, to ThroughputMode) error {
+		migrated = append(migrated, to)
+		// the service picks the maximum when migrating; the fake offer is replaced directly
+		_, err := resource.ReplaceThroughput(ctx, azcosmos.NewAutoscaleThroughputProperties(1000), nil)
+		return err
+	}
+	info, err := SwitchToAutoscaleThroughput(container, 4000, &ScaleOptions{Migrate: migrate, PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, []ThroughputMode{ThroughputAutoscale}, migrated)
+	assert.Equal(t, ThroughputAutoscale, info.Mode)
+	assert.Equal(t, int32(4000), info.Throughput)
+}
+
+func TestGetThroughput_SharedThroughput(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	throughput := azcosmos.NewManualThroughputProperties(400)
+	db, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "db"}, &azcosmos.CreateDatabaseOptions{ThroughputProperties: &throughput})
+	require.NoError(t, err)
+	container, err := CreateContainerIfNotExists(db, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+	}, nil)
+	require.NoError(t, err)
+
+	info, err := GetThroughput(db)
+	require.NoError(t, err)
+	assert.Equal(t, int32(400), info.Throughput)
+
+	_, err = GetThroughput(container)
+	var opErr *cosmosdb_errors.OperationError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "ReadThroughput", opErr.Operation)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrNotFound)
+}
+
+func TestThroughput_Serverless(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	db, err := CreateDatabaseIfNotExists(client, azcosmos.DatabaseProperties{ID: "db"}, nil)
+	require.NoError(t, err)
+	container, err := CreateContainerIfNotExists(db, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+	}, nil)
+	require.NoError(t, err)
+	createItems(t, container, 2)
+	account.SetServerless()
+
+	_, err = GetThroughput(container)
+	assert.ErrorIs(t, err, ErrServerless)
+	assert.Equal(t, http.StatusBadRequest, cosmosdb_errors.GetError(err).Status)
+
+	serverless := &ThroughputInfo{Mode: ThroughputServerless}
+	for entry, err := range ListContainers(db, &ListOptions{IncludeThroughput: true}) {
+		require.NoError(t, err)
+		assert.Equal(t, serverless, entry.Throughput)
+		assert.Equal(t, "serverless", entry.Throughput.String())
+	}
+
+	report, err := Inventory(client, nil)
+	require.NoError(t, err)
+	require.Len(t, report.Databases, 1)
+	assert.Equal(t, &ThroughputSummary{Mode: ThroughputServerless}, report.Databases[0].Throughput)
+	assert.Equal(t, &ThroughputSummary{Mode: ThroughputServerless}, report.Databases[0].Containers[0].Throughput)
+
+	// the clone is created without throughput
+	_, copied, err := CloneContainer(container, db, "orders-v2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, copied)
+}
@@ -0,0 +1,73 @@
+package cosmosdb_errors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Class groups errors by how a caller should react to them.
+type Class string
+
+const (
+	// ClassTransient errors are temporary service or network conditions and can be retried.
+	ClassTransient Class = "transient"
+	// ClassThrottled errors (HTTP 429) can be retried after the server-provided retry-after interval.
+	ClassThrottled Class = "throttled"
+	// ClassConflict errors (HTTP 409 and 412) are caused by concurrent writers. Retrying the same request does not help,
+	// but re-reading and re-applying a change may.
+	ClassConflict Class = "conflict"
+	// ClassClientError errors are caused by the request itself, e.g. a bad query, a missing item or missing permissions.
+	ClassClientError Class = "client-error"
+	// ClassFatal errors are everything else, including cancelled contexts.
+	ClassFatal Class = "fatal"
+)
+
+// StatusRetryWith (HTTP 449) is returned when a write conflicts with a concurrent operation on the server.
+// net/http has no constant for it.
+const StatusRetryWith = 449
+
+const (
+	// subStatusReadSessionNotAvailable marks a 404 returned because a replica has not caught up with the session token yet.
+	subStatusReadSessionNotAvailable = 1002
+)
+
+// Classify returns the class of err. It returns an empty Class if err is nil.
+func Classify(err error) Class {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ClassFatal
+	}
+
+	cosmosError := GetError(err)
+	switch status := cosmosError.Status; {
+	case status == 0:
+		var netErr net.Error
+		if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ClassTransient
+		}
+		return ClassFatal
+	case status == http.StatusTooManyRequests:
+		return ClassThrottled
+	case status == http.StatusConflict, status == http.StatusPreconditionFailed:
+		return ClassConflict
+	case status == http.StatusNotFound && cosmosError.SubStatus == subStatusReadSessionNotAvailable:
+		return ClassTransient
+	// 410 (Gone) is returned while partitions split or move, with the substatus telling which
+	case status == http.StatusGone, status == http.StatusRequestTimeout, status == http.StatusServiceUnavailable, status == StatusRetryWith:
+		return ClassTransient
+	case status >= 400 && status < 500:
+		return ClassClientError
+	}
+	return ClassFatal
+}
+
+// IsRetryable reports whether retrying the request that returned err may succeed, i.e. whether err is transient or throttled.
+func IsRetryable(err error) bool {
+	class := Classify(err)
+	return class == ClassTransient || class == ClassThrottled
+}
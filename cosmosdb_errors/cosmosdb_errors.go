@@ -1,15 +1,41 @@
 package cosmosdb_errors
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 )
 
-// CosmosDBError represents a structured Cosmos DB error with message and status code.
+// Cosmos DB response headers that carry error diagnostics.
+const (
+	HeaderSubStatus     = "x-ms-substatus"
+	HeaderActivityID    = "x-ms-activity-id"
+	HeaderRequestCharge = "x-ms-request-charge"
+	HeaderRetryAfterMs  = "x-ms-retry-after-ms"
+	HeaderSessionToken  = "x-ms-session-token"
+)
+
+// CosmosDBError represents a structured Cosmos DB error with message and status code,
+// along with the diagnostics returned by the service.
 type CosmosDBError struct {
 	Message string
 	Status  int
+	// SubStatus refines Status, e.g. 404/1002 (read session not available) versus a genuine 404/0 not found.
+	SubStatus int
+	// Code is the error code from the response body, e.g. "NotFound" or "TooManyRequests".
+	Code string
+	// ServiceMessage is the message from the response body.
+	ServiceMessage string
+	ActivityID     string
+	RequestCharge  float64
+	// RetryAfter is the interval the service asked the client to wait before retrying. It is zero if not provided.
+	RetryAfter   time.Duration
+	SessionToken string
 }
 
 // GetError extracts a CosmosDBError from a generic error, if possible.
@@ -21,5 +47,46 @@ func GetError(err error) CosmosDBError {
 		return CosmosDBError{}
 	}
 
-	return CosmosDBError{Message: err.Error(), Status: respErr.StatusCode}
+	cosmosError := CosmosDBError{Message: err.Error(), Status: respErr.StatusCode, Code: respErr.ErrorCode}
+
+	resp := respErr.RawResponse
+	if resp == nil {
+		return cosmosError
+	}
+
+	cosmosError.SubStatus, _ = strconv.Atoi(resp.Header.Get(HeaderSubStatus))
+	cosmosError.ActivityID = resp.Header.Get(HeaderActivityID)
+	cosmosError.RequestCharge, _ = strconv.ParseFloat(resp.Header.Get(HeaderRequestCharge), 64)
+	if ms, err := strconv.ParseFloat(resp.Header.Get(HeaderRetryAfterMs), 64); err == nil && ms > 0 {
+		cosmosError.RetryAfter = time.Duration(ms * float64(time.Millisecond))
+	}
+	cosmosError.SessionToken = resp.Header.Get(HeaderSessionToken)
+
+	code, message := parseBody(resp)
+	if code != "" {
+		cosmosError.Code = code
+	}
+	cosmosError.ServiceMessage = message
+
+	return cosmosError
+}
+
+// parseBody reads the code and message of a Cosmos DB error body, e.g. {"code":"NotFound","message":"..."}.
+// The body is cached by azcore, so it can be read again.
+func parseBody(resp *http.Response) (code, message string) {
+	if resp.Body == nil {
+		return "", ""
+	}
+	payload, err := runtime.Payload(resp)
+	if err != nil || len(payload) == 0 {
+		return "", ""
+	}
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return "", ""
+	}
+	return body.Code, body.Message
 }
@@ -1,11 +1,22 @@
 package cosmosdb_errors
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetError_WithResponseError(t *testing.T) {
@@ -27,3 +38,126 @@ func TestGetError_WithNonResponseError(t *testing.T) {
 	assert.Equal(t, 0, result.Status)
 	assert.Empty(t, result.Message)
 }
+
+func TestGetError_Diagnostics(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"code":"NotFound","message":"Message: read session not available"}`)),
+		Request:    httptest.NewRequest(http.MethodGet, "https://localhost/dbs/db/colls/c/docs/1", nil),
+	}
+	resp.Header.Set("x-ms-substatus", "1002")
+	resp.Header.Set("x-ms-activity-id", "a1b2")
+	resp.Header.Set("x-ms-request-charge", "1.24")
+	resp.Header.Set("x-ms-retry-after-ms", "250")
+	resp.Header.Set("x-ms-session-token", "0:1#42")
+
+	result := GetError(runtime.NewResponseError(resp))
+	assert.Equal(t, http.StatusNotFound, result.Status)
+	assert.Equal(t, 1002, result.SubStatus)
+	assert.Equal(t, "NotFound", result.Code)
+	assert.Equal(t, "Message: read session not available", result.ServiceMessage)
+	assert.Equal(t, "a1b2", result.ActivityID)
+	assert.Equal(t, 1.24, result.RequestCharge)
+	assert.Equal(t, 250*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, "0:1#42", result.SessionToken)
+}
+
+func TestWrap_Sentinels(t *testing.T) {
+	tests := []struct {
+		status    int
+		subStatus int
+		target    error
+	}{
+		{http.StatusNotFound, 0, ErrNotFound},
+		{http.StatusConflict, 0, ErrConflict},
+		{http.StatusPreconditionFailed, 0, ErrPreconditionFailed},
+		{http.StatusTooManyRequests, 0, ErrThrottled},
+		{http.StatusRequestEntityTooLarge, 0, ErrRequestTooLarge},
+		{http.StatusUnauthorized, 0, ErrUnauthorized},
+		{http.StatusForbidden, 5301, ErrUnauthorized},
+		{http.StatusForbidden, 1028, ErrUnauthorized},
+		{http.StatusServiceUnavailable, 0, ErrServiceUnavailable},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("reading item: %w", Wrap(responseError(tt.status, tt.subStatus)))
+		assert.ErrorIs(t, err, tt.target, "status %d/%d", tt.status, tt.subStatus)
+	}
+
+	err := Wrap(&azcore.ResponseError{StatusCode: http.StatusBadRequest})
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
+func TestWrap_SubStatus(t *testing.T) {
+	// read session not available: the replica is lagging, the item may exist
+	err := Wrap(responseError(http.StatusNotFound, subStatusReadSessionNotAvailable))
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, ErrServiceUnavailable)
+	assert.Equal(t, http.StatusServiceUnavailable, HTTPStatus(err))
+
+	// write forbidden in this region and account not found are not credential problems
+	for _, subStatus := range []int{0, 3, 1008} {
+		err := Wrap(responseError(http.StatusForbidden, subStatus))
+		assert.NotErrorIs(t, err, ErrUnauthorized, "substatus %d", subStatus)
+	}
+}
+
+// responseError returns a response error with a status and substatus.
+func responseError(status, subStatus int) error {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	resp.Header.Set("x-ms-substatus", strconv.Itoa(subStatus))
+	return &azcore.ResponseError{StatusCode: status, RawResponse: resp}
+}
+
+func TestWrap_AsAndUnwrap(t *testing.T) {
+	respErr := &azcore.ResponseError{ErrorCode: "NotFound", StatusCode: http.StatusNotFound}
+	err := fmt.Errorf("reading item: %w", Wrap(respErr))
+
+	var cosmosError CosmosDBError
+	require.ErrorAs(t, err, &cosmosError)
+	assert.Equal(t, http.StatusNotFound, cosmosError.Status)
+
+	var unwrapped *azcore.ResponseError
+	require.ErrorAs(t, err, &unwrapped)
+	assert.Same(t, respErr, unwrapped)
+
+	assert.Same(t, err, Wrap(err), "errors are not wrapped twice")
+	assert.NoError(t, Wrap(nil))
+
+	plainErr := errors.New("some error")
+	assert.Same(t, plainErr, Wrap(plainErr))
+}
+
+func TestClassify(t *testing.T) {
+	responseError := func(status int, subStatus string) error {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		resp.Header.Set("x-ms-substatus", subStatus)
+		return &azcore.ResponseError{StatusCode: status, RawResponse: resp}
+	}
+
+	tests := []struct {
+		err  error
+		want Class
+	}{
+		{nil, ""},
+		{responseError(http.StatusTooManyRequests, "3200"), ClassThrottled},
+		{responseError(http.StatusServiceUnavailable, ""), ClassTransient},
+		{responseError(http.StatusGone, "1007"), ClassTransient},
+		{responseError(http.StatusRequestTimeout, ""), ClassTransient},
+		{responseError(http.StatusNotFound, "1002"), ClassTransient},
+		{responseError(http.StatusNotFound, "0"), ClassClientError},
+		{responseError(http.StatusBadRequest, ""), ClassClientError},
+		{responseError(http.StatusConflict, ""), ClassConflict},
+		{responseError(http.StatusPreconditionFailed, ""), ClassConflict},
+		{responseError(http.StatusInternalServerError, ""), ClassFatal},
+		{fmt.Errorf("read: %w", context.DeadlineExceeded), ClassFatal},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, ClassTransient},
+		{errors.New("some error"), ClassFatal},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Classify(tt.err), "%v", tt.err)
+	}
+
+	assert.True(t, IsRetryable(responseError(http.StatusTooManyRequests, "")))
+	assert.False(t, IsRetryable(responseError(http.StatusConflict, "")))
+}
@@ -0,0 +1,26 @@
+module github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors/grpcstatus
+
+go 1.23.6
+
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.16.0
+	github.com/abhirockzz/cosmosdb-go-sdk-helper v0.0.0-20261017044217-d13cfddceff1
+	github.com/stretchr/testify v1.10.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7
+	google.golang.org/grpc v1.75.1
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/internal v1.10.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Local development and CI build against the root module of this repository.
+// Consumers ignore this directive and use the version required above.
+replace github.com/abhirockzz/cosmosdb-go-sdk-helper => ../..
@@ -0,0 +1,106 @@
+// Package grpcstatus translates Cosmos DB errors into gRPC statuses.
+// It is a separate module so that only services that use gRPC depend on gRPC and its dependencies.
+package grpcstatus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail attached to statuses.
+const ErrorDomain = "documents.azure.com"
+
+// Code returns the gRPC code for err.
+func Code(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrServiceUnavailable):
+		// including 404/1002: a lagging replica does not mean that the resource does not exist
+		return codes.Unavailable
+	}
+	switch cosmosdb_errors.GetError(err).Status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		// an ETag mismatch is a concurrency conflict that the client can resolve by retrying its read-modify-write
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusServiceUnavailable, http.StatusGone, cosmosdb_errors.StatusRetryWith:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+// FromError translates err into a gRPC status. The status carries an ErrorInfo detail with the Cosmos DB error code
+// and status, a RequestInfo detail with the activity ID (unless omitted), and a RetryInfo detail for throttled requests.
+// It returns nil if err is nil.
+func FromError(err error, opts *cosmosdb_errors.MappingOptions) *status.Status {
+	if err == nil {
+		return nil
+	}
+	code := Code(err)
+	problem := cosmosdb_errors.ToProblem(err, opts)
+
+	message := problem.Detail
+	if message == "" {
+		message = code.String()
+	}
+	st := status.New(code, message)
+
+	cosmosError := cosmosdb_errors.GetError(err)
+	if cosmosError.Status == 0 {
+		return st
+	}
+
+	var details []protoadapt.MessageV1
+	if code != codes.Internal {
+		details = append(details, &errdetails.ErrorInfo{
+			Reason: cosmosError.Code,
+			Domain: ErrorDomain,
+			Metadata: map[string]string{
+				"status":    strconv.Itoa(cosmosError.Status),
+				"substatus": strconv.Itoa(cosmosError.SubStatus),
+			},
+		})
+	}
+	if problem.ActivityID != "" {
+		details = append(details, &errdetails.RequestInfo{RequestId: problem.ActivityID})
+	}
+	if cosmosError.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(cosmosError.RetryAfter)})
+	}
+	if len(details) == 0 {
+		return st
+	}
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st
+	}
+	return withDetails
+}
+
+// Error is like FromError but returns the status as an error, for returning from a gRPC handler.
+func Error(err error, opts *cosmosdb_errors.MappingOptions) error {
+	return FromError(err, opts).Err()
+}
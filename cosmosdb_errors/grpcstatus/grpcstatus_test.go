@@ -0,0 +1,79 @@
+package grpcstatus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newResponseError(status int, headers map[string]string, body string) error {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    httptest.NewRequest(http.MethodGet, "https://localhost/dbs/db/colls/c/docs/1", nil),
+	}
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	return runtime.NewResponseError(resp)
+}
+
+func TestCode(t *testing.T) {
+	tests := map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusNotFound:            codes.NotFound,
+		http.StatusConflict:            codes.AlreadyExists,
+		http.StatusPreconditionFailed:  codes.Aborted,
+		http.StatusTooManyRequests:     codes.ResourceExhausted,
+		http.StatusRequestTimeout:      codes.DeadlineExceeded,
+		http.StatusServiceUnavailable:  codes.Unavailable,
+		http.StatusForbidden:           codes.Internal,
+		http.StatusInternalServerError: codes.Internal,
+	}
+	for cosmosStatus, want := range tests {
+		assert.Equal(t, want, Code(newResponseError(cosmosStatus, nil, "")), "cosmos status %d", cosmosStatus)
+	}
+	readSessionNotAvailable := newResponseError(http.StatusNotFound, map[string]string{"x-ms-substatus": "1002"}, "")
+	assert.Equal(t, codes.Unavailable, Code(readSessionNotAvailable))
+	assert.Equal(t, codes.OK, Code(nil))
+	assert.Equal(t, codes.Canceled, Code(context.Canceled))
+}
+
+func TestFromError(t *testing.T) {
+	err := newResponseError(http.StatusTooManyRequests, map[string]string{
+		"x-ms-activity-id":    "a1b2",
+		"x-ms-substatus":      "3200",
+		"x-ms-retry-after-ms": "250",
+	}, `{"code":"TooManyRequests","message":"Request rate is large"}`)
+
+	st, ok := status.FromError(Error(err, nil))
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, "ResourceExhausted", st.Message(), "the message is redacted by default")
+
+	details := st.Details()
+	require.Len(t, details, 3)
+	assert.Equal(t, "TooManyRequests", details[0].(*errdetails.ErrorInfo).Reason)
+	assert.Equal(t, "3200", details[0].(*errdetails.ErrorInfo).Metadata["substatus"])
+	assert.Equal(t, "a1b2", details[1].(*errdetails.RequestInfo).RequestId)
+	assert.Equal(t, 250*time.Millisecond, details[2].(*errdetails.RetryInfo).RetryDelay.AsDuration())
+
+	st = FromError(err, &cosmosdb_errors.MappingOptions{IncludeMessage: true, OmitActivityID: true})
+	assert.Equal(t, "Request rate is large", st.Message())
+	assert.Len(t, st.Details(), 2)
+
+	assert.Nil(t, FromError(nil, nil))
+}
@@ -0,0 +1,95 @@
+package cosmosdb_errors
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// headerPartitionKey is the request header that carries the partition key of an item operation.
+const headerPartitionKey = "x-ms-documentdb-partitionkey"
+
+// OperationError records the operation and resource of a failed helper call, along with the RU charge of the failed request.
+// It wraps the original error, so errors.Is, errors.As and GetError see through it.
+type OperationError struct {
+	// Operation is the name of the Cosmos DB operation, e.g. "CreateItem" or "ReadContainer".
+	Operation string
+	Database  string
+	Container string
+	ItemID    string
+	// PartitionKey is the JSON representation of the partition key, e.g. ["c42"].
+	PartitionKey  string
+	RequestCharge float64
+	Err           error
+}
+
+// NewOperationError returns e as an error, wrapping e.Err with Wrap. Empty resource fields and the request charge
+// are filled in from the failed request, if e.Err is a Cosmos DB response error.
+// It returns nil if e.Err is nil, and e.Err unchanged if it already is an *OperationError,
+// so that helpers built on other helpers report the innermost operation.
+func NewOperationError(e OperationError) error {
+	if e.Err == nil {
+		return nil
+	}
+	var opErr *OperationError
+	if errors.As(e.Err, &opErr) {
+		return e.Err
+	}
+
+	e.Err = Wrap(e.Err)
+	if e.RequestCharge == 0 {
+		e.RequestCharge = GetError(e.Err).RequestCharge
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(e.Err, &respErr) && respErr.RawResponse != nil && respErr.RawResponse.Request != nil {
+		request := respErr.RawResponse.Request
+		database, container, itemID := parseResourcePath(request.URL.Path)
+		e.Database = cmp.Or(e.Database, database)
+		e.Container = cmp.Or(e.Container, container)
+		e.ItemID = cmp.Or(e.ItemID, itemID)
+		e.PartitionKey = cmp.Or(e.PartitionKey, request.Header.Get(headerPartitionKey))
+	}
+	return &e
+}
+
+func (e *OperationError) Error() string {
+	var resource []string
+	add := func(name, value string) {
+		if value != "" {
+			resource = append(resource, fmt.Sprintf("%s %q", name, value))
+		}
+	}
+	add("database", e.Database)
+	add("container", e.Container)
+	add("item", e.ItemID)
+	if e.PartitionKey != "" {
+		resource = append(resource, "partition key "+e.PartitionKey)
+	}
+	if len(resource) == 0 {
+		return fmt.Sprintf("%s failed: %v", e.Operation, e.Err)
+	}
+	return fmt.Sprintf("%s failed (%s): %v", e.Operation, strings.Join(resource, ", "), e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// parseResourcePath extracts the database, container and item id from a resource path such as /dbs/db/colls/c/docs/1.
+func parseResourcePath(path string) (database, container, itemID string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+1 < len(segments); i += 2 {
+		switch segments[i] {
+		case "dbs":
+			database = segments[i+1]
+		case "colls":
+			container = segments[i+1]
+		case "docs":
+			itemID = segments[i+1]
+		}
+	}
+	return database, container, itemID
+}
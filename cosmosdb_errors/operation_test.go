@@ -0,0 +1,33 @@
+package cosmosdb_errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOperationError(t *testing.T) {
+	respErr := newResponseError(http.StatusNotFound, map[string]string{"x-ms-request-charge": "1.24"}, `{"code":"NotFound","message":"missing"}`)
+
+	err := NewOperationError(OperationError{Operation: "ReadItem", Container: "c", Err: respErr})
+
+	var opErr *OperationError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "db", opErr.Database, "filled in from the request path")
+	assert.Equal(t, "c", opErr.Container)
+	assert.Equal(t, "1", opErr.ItemID)
+	assert.Equal(t, 1.24, opErr.RequestCharge)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, http.StatusNotFound, GetError(err).Status)
+	assert.Contains(t, err.Error(), `ReadItem failed (database "db", container "c", item "1"): `)
+
+	wrapped := fmt.Errorf("context: %w", err)
+	assert.Same(t, wrapped, NewOperationError(OperationError{Operation: "Outer", Err: wrapped}), "the innermost operation is kept")
+
+	assert.NoError(t, NewOperationError(OperationError{Operation: "ReadItem"}))
+	assert.EqualError(t, NewOperationError(OperationError{Operation: "CreateItem", Err: errors.New("boom")}), "CreateItem failed: boom")
+}
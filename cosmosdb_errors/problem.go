@@ -0,0 +1,114 @@
+package cosmosdb_errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// MappingOptions configures how Cosmos DB errors are translated into client-facing errors,
+// by ToProblem and WriteProblem here and by the grpcstatus package.
+type MappingOptions struct {
+	// IncludeMessage includes the message of the error in the client-facing error. By default it is redacted,
+	// since Cosmos DB messages can reveal internal details such as resource names and partition key ranges.
+	IncludeMessage bool
+	// Redact, if set, is applied to the message when IncludeMessage is true, e.g. to strip account names.
+	Redact func(string) string
+	// OmitActivityID leaves out the Cosmos DB activity ID, which is otherwise attached so that a client-reported
+	// error can be traced to the service request.
+	OmitActivityID bool
+}
+
+// message returns the client-facing message for err, or an empty string if it is redacted.
+func (o *MappingOptions) message(err error) string {
+	if o == nil || !o.IncludeMessage {
+		return ""
+	}
+	message := err.Error()
+	if cosmosError := GetError(err); cosmosError.ServiceMessage != "" {
+		message = cosmosError.ServiceMessage
+	}
+	if o.Redact != nil {
+		message = o.Redact(message)
+	}
+	return message
+}
+
+// activityID returns the activity ID of err, unless it is omitted.
+func (o *MappingOptions) activityID(err error) string {
+	if o != nil && o.OmitActivityID {
+		return ""
+	}
+	return GetError(err).ActivityID
+}
+
+// Problem is an RFC 7807 problem details object, extended with the Cosmos DB error code and activity ID.
+type Problem struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Instance   string `json:"instance,omitempty"`
+	Code       string `json:"code,omitempty"`
+	ActivityID string `json:"activityId,omitempty"`
+}
+
+// HTTPStatus returns the status code to send to a client for err.
+// Cosmos DB statuses that describe the request (e.g. 404, 409, 412, 429) are passed through,
+// while failures of the service's own access to Cosmos DB are reported as 500, 503 or 504.
+func HTTPStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	if errors.Is(Wrap(err), ErrServiceUnavailable) {
+		// including 404/1002: a lagging replica does not mean that the resource does not exist
+		return http.StatusServiceUnavailable
+	}
+	switch status := GetError(err).Status; status {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed,
+		http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return status
+	case http.StatusRequestTimeout:
+		return http.StatusGatewayTimeout
+	case http.StatusServiceUnavailable, http.StatusGone, StatusRetryWith:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// ToProblem translates err into problem details.
+func ToProblem(err error, opts *MappingOptions) Problem {
+	status := HTTPStatus(err)
+	problem := Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     opts.message(err),
+		ActivityID: opts.activityID(err),
+	}
+	if status != http.StatusInternalServerError {
+		problem.Code = GetError(err).Code
+	}
+	return problem
+}
+
+// WriteProblem writes err to w as an application/problem+json response.
+// For throttled and unavailable responses, the retry-after interval provided by Cosmos DB is sent as a Retry-After header.
+func WriteProblem(w http.ResponseWriter, err error, opts *MappingOptions) {
+	problem := ToProblem(err, opts)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	if problem.Status == http.StatusTooManyRequests || problem.Status == http.StatusServiceUnavailable {
+		if retryAfter := GetError(err).RetryAfter; retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+	}
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,92 @@
+package cosmosdb_errors
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newResponseError(status int, headers map[string]string, body string) error {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    httptest.NewRequest(http.MethodGet, "https://localhost/dbs/db/colls/c/docs/1", nil),
+	}
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	if body != "" {
+		resp.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return runtime.NewResponseError(resp)
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := map[int]int{
+		http.StatusNotFound:            http.StatusNotFound,
+		http.StatusConflict:            http.StatusConflict,
+		http.StatusPreconditionFailed:  http.StatusPreconditionFailed,
+		http.StatusTooManyRequests:     http.StatusTooManyRequests,
+		http.StatusBadRequest:          http.StatusBadRequest,
+		http.StatusUnauthorized:        http.StatusInternalServerError,
+		http.StatusForbidden:           http.StatusInternalServerError,
+		http.StatusRequestTimeout:      http.StatusGatewayTimeout,
+		http.StatusGone:                http.StatusServiceUnavailable,
+		http.StatusServiceUnavailable:  http.StatusServiceUnavailable,
+		http.StatusInternalServerError: http.StatusInternalServerError,
+	}
+	for cosmosStatus, want := range tests {
+		assert.Equal(t, want, HTTPStatus(newResponseError(cosmosStatus, nil, "")), "cosmos status %d", cosmosStatus)
+	}
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatus(errors.New("boom")))
+}
+
+func TestWriteProblem(t *testing.T) {
+	err := newResponseError(http.StatusTooManyRequests, map[string]string{
+		"x-ms-activity-id":    "a1b2",
+		"x-ms-retry-after-ms": "1500",
+	}, `{"code":"TooManyRequests","message":"Request rate is large. Account: acme-prod"}`)
+
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, err, nil)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	var problem Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	assert.Equal(t, Problem{
+		Type:       "about:blank",
+		Title:      "Too Many Requests",
+		Status:     http.StatusTooManyRequests,
+		Code:       "TooManyRequests",
+		ActivityID: "a1b2",
+	}, problem, "the message is redacted by default")
+}
+
+func TestToProblem_Options(t *testing.T) {
+	err := newResponseError(http.StatusNotFound, map[string]string{"x-ms-activity-id": "a1b2"},
+		`{"code":"NotFound","message":"Resource not found. Account: acme-prod"}`)
+
+	problem := ToProblem(err, &MappingOptions{
+		IncludeMessage: true,
+		Redact:         func(s string) string { return strings.ReplaceAll(s, "acme-prod", "***") },
+		OmitActivityID: true,
+	})
+	assert.Equal(t, "Resource not found. Account: ***", problem.Detail)
+	assert.Empty(t, problem.ActivityID)
+
+	problem = ToProblem(newResponseError(http.StatusUnauthorized, nil, `{"code":"Unauthorized","message":"bad key"}`), nil)
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.Empty(t, problem.Code, "internal error codes are not exposed")
+}
@@ -0,0 +1,121 @@
+package cosmosdb_errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Sentinel errors for the Cosmos DB status codes that callers commonly handle.
+// Errors returned by the helpers in this module match them with errors.Is, e.g.
+//
+//	if errors.Is(err, cosmosdb_errors.ErrNotFound) { ... }
+//
+// Errors returned directly by the Azure SDK can be matched after passing them through Wrap.
+var (
+	ErrNotFound           = errors.New("cosmos db: not found")
+	ErrConflict           = errors.New("cosmos db: conflict")
+	ErrPreconditionFailed = errors.New("cosmos db: precondition failed")
+	ErrThrottled          = errors.New("cosmos db: request rate too large")
+	ErrRequestTooLarge    = errors.New("cosmos db: request entity too large")
+	ErrUnauthorized       = errors.New("cosmos db: unauthorized")
+	ErrServiceUnavailable = errors.New("cosmos db: service unavailable")
+)
+
+// Substatuses of HTTP 403 that report missing or insufficient credentials. Other 403 substatuses describe
+// the state of the account or resource, e.g. 403/3 (writes forbidden in this region) or 403/1008 (account not found).
+const (
+	subStatusAuthorizationFailed = 1028
+	// 5300-5400 are the RBAC denials of Microsoft Entra ID principals, e.g. a missing data action.
+	subStatusRBACFirst = 5300
+	subStatusRBACLast  = 5400
+)
+
+// sentinel returns the sentinel error for a Cosmos DB error, or nil if there is none.
+func sentinel(e CosmosDBError) error {
+	switch e.Status {
+	case http.StatusNotFound:
+		if e.SubStatus == subStatusReadSessionNotAvailable {
+			// a replica has not caught up with the session yet: the resource may well exist
+			return ErrServiceUnavailable
+		}
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case http.StatusTooManyRequests:
+		return ErrThrottled
+	case http.StatusRequestEntityTooLarge:
+		return ErrRequestTooLarge
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		if isAuthSubStatus(e.SubStatus) {
+			return ErrUnauthorized
+		}
+	case http.StatusServiceUnavailable:
+		return ErrServiceUnavailable
+	}
+	return nil
+}
+
+// isAuthSubStatus reports whether the substatus of a 403 response is about credentials or permissions.
+func isAuthSubStatus(subStatus int) bool {
+	return subStatus == subStatusAuthorizationFailed || subStatus >= subStatusRBACFirst && subStatus <= subStatusRBACLast
+}
+
+// Error implements the error interface, so that a CosmosDBError can be the target of errors.As.
+func (e CosmosDBError) Error() string {
+	return e.Message
+}
+
+// Is reports whether the error matches target, one of the sentinel errors of this package.
+func (e CosmosDBError) Is(target error) bool {
+	s := sentinel(e)
+	return s != nil && s == target
+}
+
+// Error wraps a Cosmos DB response error. It matches the sentinel errors of this package with errors.Is,
+// can be extracted as a CosmosDBError with errors.As, and unwraps to the underlying *azcore.ResponseError.
+type Error struct {
+	CosmosDBError
+	err error
+}
+
+// Wrap returns err wrapped in an *Error if it is a Cosmos DB response error, and err unchanged otherwise.
+// Wrap returns nil if err is nil, and does not wrap an error twice.
+func Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var wrapped *Error
+	if errors.As(err, &wrapped) {
+		return err
+	}
+	cosmosError := GetError(err)
+	if cosmosError.Status == 0 {
+		return err
+	}
+	return &Error{CosmosDBError: cosmosError, err: err}
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+func (e *Error) Is(target error) bool {
+	return e.CosmosDBError.Is(target)
+}
+
+// As sets target to the CosmosDBError of e if target is a *CosmosDBError.
+func (e *Error) As(target any) bool {
+	if t, ok := target.(*CosmosDBError); ok {
+		*t = e.CosmosDBError
+		return true
+	}
+	return false
+}
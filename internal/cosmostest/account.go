@@ -0,0 +1,451 @@
+package cosmostest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// Account is an in-memory fake of a Cosmos DB account: databases, containers, their throughput offers and items.
+// Pass its Handle method to NewClient. Requests for other resources are answered with 404.
+// Partition keys are not modelled: item ids are unique per container, and queries return all items of a container.
+type Account struct {
+	mu         sync.Mutex
+	databases  map[string]*database
+	offers     map[string]map[string]any // by offer id
+	nextRID    int
+	nextEtag   int
+	requests   []string
+	pending    int
+	serverless bool
+}
+
+type database struct {
+	rid        string
+	containers map[string]azcosmos.ContainerProperties
+	items      map[string][]map[string]any // by container id, in insertion order
+}
+
+// NewAccount returns an empty account.
+func NewAccount() *Account {
+	return &Account{databases: map[string]*database{}, offers: map[string]map[string]any{}}
+}
+
+// SetServerless makes the account behave like a serverless account, which answers requests for offers with 400.
+func (a *Account) SetServerless() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.serverless = true
+}
+
+// SetReplacePending makes the next reads of offers report a pending throughput replace.
+func (a *Account) SetReplacePending(reads int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = reads
+}
+
+// Requests returns the method and path of every request served so far, e.g. "POST /dbs/db/colls".
+func (a *Account) Requests() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.requests...)
+}
+
+// Writes returns the requests served so far that are not reads or queries.
+func (a *Account) Writes() []string {
+	var writes []string
+	for _, request := range a.Requests() {
+		if !strings.HasPrefix(request, http.MethodGet+" ") && !strings.HasPrefix(request, "QUERY ") {
+			writes = append(writes, request)
+		}
+	}
+	return writes
+}
+
+var offerQuery = regexp.MustCompile(`offerResourceId = '([^']*)'`)
+
+// Handle serves a Cosmos DB request.
+func (a *Account) Handle(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	method := r.Method
+	if IsQuery(r) {
+		method = "QUERY"
+	}
+	a.requests = append(a.requests, method+" "+r.URL.Path)
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(segments) == 1 && segments[0] == "dbs":
+		a.databasesFeed(w, r, method)
+	case len(segments) == 2 && segments[0] == "dbs":
+		a.database(w, method, segments[1])
+	case len(segments) == 3 && segments[0] == "dbs" && segments[2] == "colls":
+		a.containersFeed(w, r, method, segments[1])
+	case len(segments) == 4 && segments[0] == "dbs" && segments[2] == "colls":
+		a.container(w, r, method, segments[1], segments[3])
+	case len(segments) == 5 && segments[0] == "dbs" && segments[2] == "colls" && segments[4] == "docs":
+		a.itemsFeed(w, r, method, segments[1], segments[3])
+	case len(segments) == 6 && segments[0] == "dbs" && segments[2] == "colls" && segments[4] == "docs":
+		a.item(w, r, method, segments[1], segments[3], segments[5])
+	case segments[0] == "offers" && a.serverless:
+		WriteError(w, http.StatusBadRequest, "BadRequest", "Reading or replacing offers is not supported for serverless accounts.")
+	case len(segments) == 1 && segments[0] == "offers" && method == "QUERY":
+		a.queryOffers(w, r)
+	case len(segments) == 2 && segments[0] == "offers":
+		a.offer(w, r, method, segments[1])
+	default:
+		WriteError(w, http.StatusNotFound, "NotFound", "resource not found")
+	}
+}
+
+func (a *Account) databasesFeed(w http.ResponseWriter, r *http.Request, method string) {
+	switch method {
+	case "QUERY":
+		databases := []azcosmos.DatabaseProperties{}
+		for id, db := range a.databases {
+			databases = append(databases, azcosmos.DatabaseProperties{ID: id, ResourceID: db.rid})
+		}
+		WriteJSON(w, http.StatusOK, map[string]any{"Databases": databases})
+	case http.MethodPost:
+		var props azcosmos.DatabaseProperties
+		if err := json.NewDecoder(r.Body).Decode(&props); err != nil {
+			WriteError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		if _, ok := a.databases[props.ID]; ok {
+			WriteError(w, http.StatusConflict, "Conflict", "database already exists")
+			return
+		}
+		db := &database{rid: a.newRID(), containers: map[string]azcosmos.ContainerProperties{}, items: map[string][]map[string]any{}}
+		a.databases[props.ID] = db
+		a.createOffer(r, db.rid)
+		WriteJSON(w, http.StatusCreated, azcosmos.DatabaseProperties{ID: props.ID, ResourceID: db.rid})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *Account) database(w http.ResponseWriter, method, id string) {
+	db, ok := a.databases[id]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "NotFound", "database not found")
+		return
+	}
+	switch method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, azcosmos.DatabaseProperties{ID: id, ResourceID: db.rid})
+	case http.MethodDelete:
+		delete(a.databases, id)
+		a.deleteOffer(db.rid)
+		for _, container := range db.containers {
+			a.deleteOffer(container.ResourceID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *Account) containersFeed(w http.ResponseWriter, r *http.Request, method, databaseID string) {
+	db, ok := a.databases[databaseID]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "NotFound", "database not found")
+		return
+	}
+	switch method {
+	case "QUERY":
+		containers := []azcosmos.ContainerProperties{}
+		for _, container := range db.containers {
+			containers = append(containers, container)
+		}
+		WriteJSON(w, http.StatusOK, map[string]any{"DocumentCollections": containers})
+	case http.MethodPost:
+		var props azcosmos.ContainerProperties
+		if err := json.NewDecoder(r.Body).Decode(&props); err != nil {
+			WriteError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		if _, ok := db.containers[props.ID]; ok {
+			WriteError(w, http.StatusConflict, "Conflict", "container already exists")
+			return
+		}
+		props.ResourceID = a.newRID()
+		db.containers[props.ID] = props
+		a.createOffer(r, props.ResourceID)
+		WriteJSON(w, http.StatusCreated, props)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *Account) container(w http.ResponseWriter, r *http.Request, method, databaseID, id string) {
+	db, ok := a.databases[databaseID]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "NotFound", "database not found")
+		return
+	}
+	container, ok := db.containers[id]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "NotFound", "container not found")
+		return
+	}
+	switch method {
+	case http.MethodGet:
+		if r.Header.Get("x-ms-documentdb-populatequotainfo") == "true" {
+			w.Header().Set("x-ms-resource-usage", fmt.Sprintf("documentsSize=0;documentsCount=%d;collectionSize=0", len(db.items[id])))
+		}
+		WriteJSON(w, http.StatusOK, container)
+	case http.MethodPut:
+		var props azcosmos.ContainerProperties
+		if err := json.NewDecoder(r.Body).Decode(&props); err != nil {
+			WriteError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		if fmt.Sprint(props.PartitionKeyDefinition.Paths) != fmt.Sprint(container.PartitionKeyDefinition.Paths) {
+			WriteError(w, http.StatusBadRequest, "BadRequest", "partition key paths cannot be changed")
+			return
+		}
+		props.ResourceID = container.ResourceID
+		db.containers[id] = props
+		WriteJSON(w, http.StatusOK, props)
+	case http.MethodDelete:
+		delete(db.containers, id)
+		delete(db.items, id)
+		a.deleteOffer(container.ResourceID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Items returns copies of the items of a container, in insertion order, decoded like encoding/json decodes
+// into a map: numbers are float64.
+func (a *Account) Items(databaseID, containerID string) []map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	db, ok := a.databases[databaseID]
+	if !ok {
+		return nil
+	}
+	b, _ := json.Marshal(db.items[containerID])
+	var items []map[string]any
+	_ = json.Unmarshal(b, &items)
+	return items
+}
+
+func (a *Account) lookupContainer(w http.ResponseWriter, databaseID, id string) (*database, bool) {
+	db, ok := a.databases[databaseID]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "NotFound", "database not found")
+		return nil, false
+	}
+	if _, ok := db.containers[id]; !ok {
+		WriteError(w, http.StatusNotFound, "NotFound", "container not found")
+		return nil, false
+	}
+	return db, true
+}
+
+// itemsFeed queries and creates items. Queries return all items, paged by the x-ms-max-item-count header.
+func (a *Account) itemsFeed(w http.ResponseWriter, r *http.Request, method, databaseID, containerID string) {
+	db, ok := a.lookupContainer(w, databaseID, containerID)
+	if !ok {
+		return
+	}
+	items := db.items[containerID]
+	switch method {
+	case "QUERY":
+		offset, _ := strconv.Atoi(r.Header.Get("x-ms-continuation"))
+		offset = min(offset, len(items))
+		end := len(items)
+		if n, err := strconv.Atoi(r.Header.Get("x-ms-max-item-count")); err == nil && n > 0 {
+			end = min(offset+n, len(items))
+		}
+		continuation := ""
+		if end < len(items) {
+			continuation = strconv.Itoa(end)
+		}
+		WriteDocuments(w, append([]map[string]any{}, items[offset:end]...), continuation)
+	case http.MethodPost:
+		var item map[string]any
+		if err := decodeItem(r, &item); err != nil {
+			WriteError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		i := indexOfItem(items, item["id"])
+		if i >= 0 && !strings.EqualFold(r.Header.Get("x-ms-documentdb-is-upsert"), "true") {
+			WriteError(w, http.StatusConflict, "Conflict", "item already exists")
+			return
+		}
+		a.stampItem(item)
+		if i >= 0 {
+			items[i] = item
+			writeItem(w, http.StatusOK, item)
+			return
+		}
+		db.items[containerID] = append(items, item)
+		writeItem(w, http.StatusCreated, item)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// item reads, replaces and deletes an item, honouring the If-Match header.
+func (a *Account) item(w http.ResponseWriter, r *http.Request, method, databaseID, containerID, id string) {
+	db, ok := a.lookupContainer(w, databaseID, containerID)
+	if !ok {
+		return
+	}
+	items := db.items[containerID]
+	i := indexOfItem(items, id)
+	if i < 0 {
+		WriteError(w, http.StatusNotFound, "NotFound", "item not found")
+		return
+	}
+	if etag := r.Header.Get("If-Match"); etag != "" && etag != items[i]["_etag"] {
+		WriteError(w, http.StatusPreconditionFailed, "PreconditionFailed", "etag does not match")
+		return
+	}
+	switch method {
+	case http.MethodGet:
+		writeItem(w, http.StatusOK, items[i])
+	case http.MethodPut:
+		var item map[string]any
+		if err := decodeItem(r, &item); err != nil {
+			WriteError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		a.stampItem(item)
+		items[i] = item
+		writeItem(w, http.StatusOK, item)
+	case http.MethodDelete:
+		db.items[containerID] = append(items[:i:i], items[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeItem decodes the item in the body of a request, keeping numbers as json.Number so that they are stored exactly.
+func decodeItem(r *http.Request, item *map[string]any) error {
+	d := json.NewDecoder(r.Body)
+	d.UseNumber()
+	return d.Decode(item)
+}
+
+// writeItem writes an item response with the etag of the item in the etag header, like the service.
+func writeItem(w http.ResponseWriter, status int, item map[string]any) {
+	if etag, ok := item["_etag"].(string); ok {
+		w.Header().Set("etag", etag)
+	}
+	WriteJSON(w, status, item)
+}
+
+func (a *Account) stampItem(item map[string]any) {
+	a.nextEtag++
+	item["_etag"] = fmt.Sprintf(`"%d"`, a.nextEtag)
+}
+
+func indexOfItem(items []map[string]any, id any) int {
+	for i, item := range items {
+		if item["id"] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (a *Account) queryOffers(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	offers := []map[string]any{}
+	if match := offerQuery.FindStringSubmatch(body.Query); match != nil {
+		for _, offer := range a.offers {
+			if offer["offerResourceId"] == match[1] {
+				offers = append(offers, offer)
+			}
+		}
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"Offers": offers})
+}
+
+func (a *Account) offer(w http.ResponseWriter, r *http.Request, method, id string) {
+	offer, ok := a.offers[id]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "NotFound", "offer not found")
+		return
+	}
+	w.Header().Set("x-ms-cosmos-min-throughput", "400")
+	if a.pending > 0 {
+		a.pending--
+		w.Header().Set("x-ms-offer-replace-pending", "true")
+	}
+	switch method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, offer)
+	case http.MethodPut:
+		var body struct {
+			Content map[string]any `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			WriteError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		offer["content"] = body.Content
+		WriteJSON(w, http.StatusOK, offer)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// createOffer provisions the throughput requested by the headers of a create request, if any.
+func (a *Account) createOffer(r *http.Request, resourceRID string) {
+	content := map[string]any{}
+	if throughput := r.Header.Get("x-ms-offer-throughput"); throughput != "" {
+		n, _ := strconv.Atoi(throughput)
+		content["offerThroughput"] = n
+	} else if autoscale := r.Header.Get("x-ms-cosmos-offer-autopilot-settings"); autoscale != "" {
+		var settings map[string]any
+		_ = json.Unmarshal([]byte(autoscale), &settings)
+		content["offerAutopilotSettings"] = settings
+	} else {
+		return
+	}
+	id := a.newRID()
+	a.offers[id] = map[string]any{
+		"id":              id,
+		"_rid":            id,
+		"_self":           "offers/" + id + "/",
+		"offerResourceId": resourceRID,
+		"offerType":       "Invalid",
+		"offerVersion":    "V2",
+		"content":         content,
+	}
+}
+
+func (a *Account) deleteOffer(resourceRID string) {
+	for id, offer := range a.offers {
+		if offer["offerResourceId"] == resourceRID {
+			delete(a.offers, id)
+		}
+	}
+}
+
+func (a *Account) newRID() string {
+	a.nextRID++
+	return fmt.Sprintf("rid%d", a.nextRID)
+}
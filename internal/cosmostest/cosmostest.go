@@ -0,0 +1,101 @@
+// Package cosmostest provides an in-process fake of the Cosmos DB HTTP API for unit tests.
+package cosmostest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/require"
+)
+
+// Endpoint is the account endpoint used by test clients. No requests leave the process.
+const Endpoint = "https://localhost:8081"
+
+// Key is the well-known Cosmos DB emulator key, used by test clients.
+const Key = "C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw=="
+
+const accountProperties = `{"id":"test","writableLocations":[{"name":"East US","databaseAccountEndpoint":"https://localhost:8081/"}],"readableLocations":[{"name":"East US","databaseAccountEndpoint":"https://localhost:8081/"}],"enableMultipleWriteLocations":false}`
+
+// transport serves Cosmos DB requests from an in-process handler.
+// The account properties request issued by the SDK is answered automatically.
+type transport struct {
+	handler http.HandlerFunc
+}
+
+func (f transport) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	if req.URL.Path == "" || req.URL.Path == "/" {
+		_, _ = io.WriteString(rec, accountProperties)
+	} else {
+		f.handler(rec, req)
+	}
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// NewClient returns a client whose requests are served by handler. SDK retries are disabled.
+func NewClient(t *testing.T, handler http.HandlerFunc) *azcosmos.Client {
+	t.Helper()
+	cred, err := azcosmos.NewKeyCredential(Key)
+	require.NoError(t, err)
+	client, err := azcosmos.NewClientWithKey(Endpoint, cred, &azcosmos.ClientOptions{ClientOptions: ClientOptions(handler)})
+	require.NoError(t, err)
+	return client
+}
+
+// ClientOptions returns client options whose transport serves requests with handler, with retries disabled.
+// It is used by clients that build their own pipeline.
+func ClientOptions(handler http.HandlerFunc) azcore.ClientOptions {
+	return azcore.ClientOptions{
+		Transport: transport{handler: handler},
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+	}
+}
+
+// NewContainer returns a client for container "container" in database "db" whose requests are served by handler.
+func NewContainer(t *testing.T, handler http.HandlerFunc) *azcosmos.ContainerClient {
+	t.Helper()
+	container, err := NewClient(t, handler).NewContainer("db", "container")
+	require.NoError(t, err)
+	return container
+}
+
+// WriteJSON writes v as a JSON response with the given status code.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes a Cosmos DB error response.
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	WriteJSON(w, status, map[string]string{"code": code, "message": message})
+}
+
+// Unexpected returns a handler that fails the test on any request.
+func Unexpected(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// IsQuery reports whether the request is a query (as opposed to a point operation on the same path).
+func IsQuery(r *http.Request) bool {
+	return r.Header.Get("x-ms-documentdb-query") == "True"
+}
+
+// WriteDocuments writes a query response page. An empty continuation marks the last page.
+func WriteDocuments(w http.ResponseWriter, documents any, continuation string) {
+	if continuation != "" {
+		w.Header().Set("x-ms-continuation", continuation)
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"Documents": documents})
+}
@@ -0,0 +1,197 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/retry"
+)
+
+const (
+	defaultLockTTL   = time.Minute
+	lockID           = "lock"
+	lockPollInterval = time.Second
+)
+
+var (
+	// ErrLocked is returned by Run when another instance holds the lock.
+	ErrLocked = errors.New("migrations are locked by another instance")
+	// ErrLockLost is returned by Run when the lock expired and was taken over by another instance while migrating.
+	ErrLockLost = errors.New("migration lock was lost")
+)
+
+// lockDocument is the lock in the history container. It is held until ExpiresAt, and renewed by its owner.
+type lockDocument struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// lock is a lease on the lock document. Writes use the etag of the last write, so that a lease
+// that was taken over by another instance cannot be renewed or released.
+type lock struct {
+	database  string
+	container *azcosmos.ContainerClient
+	owner     string
+	ttl       time.Duration
+	retry     *retry.Policy
+
+	mu   sync.Mutex // guards etag, which is written by renew while the lock is kept alive
+	etag azcore.ETag
+}
+
+// acquireLock takes the lock in the history container of database, waiting up to Options.LockWait
+// for a lock held by another instance to be released or to expire.
+func acquireLock(ctx context.Context, database string, container *azcosmos.ContainerClient, o Options) (*lock, error) {
+	l := &lock{database: database, container: container, owner: o.Owner, ttl: o.LockTTL, retry: o.Retry}
+	deadline := time.Now().Add(o.LockWait)
+	for {
+		holder, err := l.tryAcquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if holder == nil {
+			return l, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w: held by %s until %s", ErrLocked, holder.Owner, holder.ExpiresAt.Format(time.RFC3339))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(lockPollInterval, remaining)):
+		}
+	}
+}
+
+// tryAcquire creates the lock document, or takes it over if it's expired or already owned by this instance.
+// It returns the lock document of the current holder if the lock is held by another instance.
+func (l *lock) tryAcquire(ctx context.Context) (*lockDocument, error) {
+	body, err := l.document()
+	if err != nil {
+		return nil, err
+	}
+	pk := azcosmos.NewPartitionKeyString(lockID)
+	resp, err := l.do(ctx, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return l.container.CreateItem(ctx, pk, body, nil)
+	})
+	if err == nil {
+		l.etag = resp.ETag
+		return nil, nil
+	}
+	if !errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrConflict) {
+		return nil, l.error("CreateItem", err)
+	}
+
+	resp, err = l.do(ctx, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return l.container.ReadItem(ctx, pk, lockID, nil)
+	})
+	if err != nil {
+		if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+			// released in the meantime
+			return l.tryAcquire(ctx)
+		}
+		return nil, l.error("ReadItem", err)
+	}
+	var holder lockDocument
+	if err := json.Unmarshal(resp.Value, &holder); err != nil {
+		return nil, err
+	}
+	if holder.Owner != l.owner && time.Now().Before(holder.ExpiresAt) {
+		return &holder, nil
+	}
+
+	etag := resp.ETag
+	resp, err = l.do(ctx, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return l.container.ReplaceItem(ctx, pk, lockID, body, &azcosmos.ItemOptions{IfMatchEtag: &etag})
+	})
+	if err != nil {
+		if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrPreconditionFailed) ||
+			errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+			// another instance took over first
+			return &holder, nil
+		}
+		return nil, l.error("ReplaceItem", err)
+	}
+	l.etag = resp.ETag
+	return nil, nil
+}
+
+// keepAlive renews the lease every half TTL until stop is called, so that a page that takes longer than the TTL
+// to migrate (e.g. with slow migration functions or long throttling pauses) does not lose the lock.
+// If renewing fails, the returned context is cancelled with the error as its cause, which stops the migration
+// before another instance can take over. stop must be called before the lock is released.
+func (l *lock) keepAlive(ctx context.Context) (_ context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(max(l.ttl/2, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.renew(ctx); err != nil {
+					cancel(err)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, func() {
+		cancel(context.Canceled)
+		<-done
+	}
+}
+
+// renew extends the lease by the TTL. It fails with ErrLockLost if another instance has taken over the lock.
+func (l *lock) renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	body, err := l.document()
+	if err != nil {
+		return err
+	}
+	resp, err := l.do(ctx, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return l.container.ReplaceItem(ctx, azcosmos.NewPartitionKeyString(lockID), lockID, body, &azcosmos.ItemOptions{IfMatchEtag: &l.etag})
+	})
+	if err != nil {
+		if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrPreconditionFailed) ||
+			errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+			return ErrLockLost
+		}
+		return l.error("ReplaceItem", err)
+	}
+	l.etag = resp.ETag
+	return nil
+}
+
+// release deletes the lock document unless another instance has taken it over.
+// Failures are ignored: the lock expires after its TTL.
+func (l *lock) release(ctx context.Context) {
+	_, _ = l.container.DeleteItem(ctx, azcosmos.NewPartitionKeyString(lockID), lockID, &azcosmos.ItemOptions{IfMatchEtag: &l.etag})
+}
+
+// do calls a Cosmos DB operation on the lock document, retrying throttled and transient failures.
+func (l *lock) do(ctx context.Context, fn func(ctx context.Context) (azcosmos.ItemResponse, error)) (azcosmos.ItemResponse, error) {
+	return retry.DoValue(ctx, l.retry, fn)
+}
+
+func (l *lock) document() ([]byte, error) {
+	return json.Marshal(lockDocument{ID: lockID, Type: "lock", Owner: l.owner, ExpiresAt: time.Now().Add(l.ttl).UTC()})
+}
+
+func (l *lock) error(operation string, err error) error {
+	return operationError(operation, l.database, l.container.ID(), lockID, err)
+}
@@ -0,0 +1,543 @@
+// Package migrate runs ordered, versioned data migrations over the documents of Cosmos DB containers.
+//
+// A migration pages through the documents selected by its query, transforms each one with a Go function
+// and replaces the documents the function changed. Applied migrations are recorded in a history container,
+// so every migration runs once. A lock in the history container makes sure only one instance runs migrations
+// at a time, e.g. when several replicas of a service start together.
+//
+// Progress is checkpointed with the continuation token of the query after every page, so a run that is
+// interrupted resumes where it stopped. The documents of an interrupted page are transformed again,
+// so migration functions must be idempotent: skip documents that already have the new shape.
+package migrate
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/common"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/operations"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/retry"
+)
+
+const (
+	defaultHistoryContainer = "migrations"
+	defaultQuery            = "SELECT * FROM c"
+	defaultPageSize         = 100
+
+	statusRunning   = "running"
+	statusCompleted = "completed"
+)
+
+// Migration is a versioned data migration of the documents of a container. Create it with Map or Typed.
+type Migration struct {
+	// Version orders the migrations. Versions must be positive and unique.
+	Version     int
+	Description string
+	// Container is the container of the documents to migrate, in the database of the Migrator.
+	Container string
+	// Query selects the documents to migrate. Defaults to "SELECT * FROM c".
+	// Narrowing it to the documents that still need migrating saves request units.
+	Query string
+
+	apply func(ctx context.Context, doc map[string]any) (map[string]any, bool, error)
+	// err is reported by New, e.g. for a nil migration function.
+	err error
+}
+
+// errNilFunc is reported by New for a migration created with a nil function.
+var errNilFunc = errors.New("migration function is nil")
+
+// Map returns a migration that modifies documents as maps. fn changes doc in place and reports whether it changed it;
+// only changed documents are replaced. Numbers are decoded as json.Number, so that the numbers fn doesn't change
+// are written back exactly, including integers that a float64 cannot represent.
+func Map(version int, description, container string, fn func(ctx context.Context, doc map[string]any) (bool, error)) Migration {
+	if fn == nil {
+		return Migration{Version: version, Description: description, Container: container, err: errNilFunc}
+	}
+	return Migration{
+		Version:     version,
+		Description: description,
+		Container:   container,
+		apply: func(ctx context.Context, doc map[string]any) (map[string]any, bool, error) {
+			changed, err := fn(ctx, doc)
+			return doc, changed, err
+		},
+	}
+}
+
+// Typed returns a migration that modifies documents decoded into T. fn changes doc in place and reports
+// whether it changed it; only changed documents are replaced. Changed documents are replaced with the JSON
+// encoding of T, so properties that T does not declare are dropped: use Map to keep them.
+func Typed[T any](version int, description, container string, fn func(ctx context.Context, doc *T) (bool, error)) Migration {
+	if fn == nil {
+		return Migration{Version: version, Description: description, Container: container, err: errNilFunc}
+	}
+	return Migration{
+		Version:     version,
+		Description: description,
+		Container:   container,
+		apply: func(ctx context.Context, doc map[string]any) (map[string]any, bool, error) {
+			var typed T
+			if err := convert(doc, &typed); err != nil {
+				return nil, false, err
+			}
+			changed, err := fn(ctx, &typed)
+			if err != nil || !changed {
+				return doc, false, err
+			}
+			var migrated map[string]any
+			if err := convert(typed, &migrated); err != nil {
+				return nil, false, err
+			}
+			return migrated, true, nil
+		},
+	}
+}
+
+// Options configures a Migrator.
+type Options struct {
+	// HistoryContainer is the container that records applied migrations and holds the lock.
+	// It is created with partition key /id if it doesn't exist. Defaults to "migrations".
+	HistoryContainer string
+	// Owner identifies this instance in the lock. Defaults to the host name and process id.
+	Owner string
+	// LockTTL is how long the lock is held without being renewed. It is renewed after every page and every half TTL,
+	// and taken over by other instances once it has expired, e.g. after a crash. Defaults to 1m.
+	LockTTL time.Duration
+	// LockWait is how long Run waits for a lock held by another instance. Zero fails immediately with ErrLocked.
+	LockWait time.Duration
+	// DryRun runs the migration functions without replacing documents, recording history or taking the lock,
+	// and reports how many documents would change. Each migration sees the documents unchanged by the previous ones.
+	DryRun bool
+	// MaxRequestUnits limits the request units spent per second by Run. Zero means no limit.
+	MaxRequestUnits float64
+	// PageSize is the number of documents per query page, and so per checkpoint. Defaults to 100.
+	PageSize int32
+	// Retry is the policy used to retry throttled and transient failures. Nil uses the defaults of the retry package.
+	Retry *retry.Policy
+}
+
+// MigrationResult reports a migration applied by Run.
+type MigrationResult struct {
+	Version     int
+	Description string
+	// Scanned is the number of documents the migration function was called with.
+	Scanned int
+	// Migrated is the number of documents that were replaced, or would be replaced in a dry run.
+	Migrated int
+	// RequestCharge is the RU charge of the queries and replaces.
+	RequestCharge float64
+	// Resumed is set if the migration continued an interrupted run.
+	Resumed bool
+}
+
+// Result reports the migrations applied by Run, in order.
+type Result struct {
+	Migrations []MigrationResult
+	DryRun     bool
+}
+
+// Record is the history entry of a migration.
+type Record struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	// Status is "running" while the migration is applied, and "completed" afterwards.
+	Status string `json:"status"`
+	// Continuation is the continuation token of the last completed page of a running migration.
+	Continuation  string     `json:"continuation,omitempty"`
+	Scanned       int        `json:"scanned"`
+	Migrated      int        `json:"migrated"`
+	RequestCharge float64    `json:"requestCharge"`
+	StartedAt     time.Time  `json:"startedAt"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+}
+
+// Migrator applies migrations to the containers of a database.
+type Migrator struct {
+	db         *azcosmos.DatabaseClient
+	migrations []Migration
+	opts       Options
+}
+
+// New returns a Migrator for migrations of the containers of db. Migrations are applied in version order.
+func New(db *azcosmos.DatabaseClient, migrations []Migration, opts *Options) (*Migrator, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	o.HistoryContainer = cmp.Or(o.HistoryContainer, defaultHistoryContainer)
+	o.LockTTL = cmp.Or(o.LockTTL, defaultLockTTL)
+	o.PageSize = cmp.Or(o.PageSize, defaultPageSize)
+	if o.Owner == "" {
+		hostname, _ := os.Hostname()
+		o.Owner = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	migrations = slices.Clone(migrations)
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	var errs []error
+	if o.LockTTL < 0 {
+		errs = append(errs, errors.New("lock TTL must not be negative"))
+	}
+	for i, migration := range migrations {
+		switch {
+		case migration.Version <= 0:
+			errs = append(errs, fmt.Errorf("migration %q: version must be positive", migration.Description))
+		case i > 0 && migrations[i-1].Version == migration.Version:
+			errs = append(errs, fmt.Errorf("migration %d is declared twice", migration.Version))
+		}
+		if migration.Container == "" {
+			errs = append(errs, fmt.Errorf("migration %d: container is required", migration.Version))
+		}
+		switch {
+		case migration.err != nil:
+			errs = append(errs, fmt.Errorf("migration %d: %w", migration.Version, migration.err))
+		case migration.apply == nil:
+			errs = append(errs, fmt.Errorf("migration %d: create migrations with Map or Typed", migration.Version))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, opts: o}, nil
+}
+
+// History returns the history records of the migrations, in version order.
+// It returns no records if the history container doesn't exist.
+func (m *Migrator) History(ctx context.Context) ([]Record, error) {
+	history, err := m.db.NewContainer(m.opts.HistoryContainer)
+	if err != nil {
+		return nil, err
+	}
+	records, err := m.readHistory(ctx, history)
+	if errors.Is(err, cosmosdb_errors.ErrNotFound) {
+		return nil, nil
+	}
+	return records, err
+}
+
+// Pending returns the migrations that have not completed, in version order.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	records, err := m.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, _, err := m.pending(records)
+	return pending, err
+}
+
+// Run applies the pending migrations in version order, holding the lock, and returns what it applied.
+// It fails with ErrLocked if another instance holds the lock for longer than Options.LockWait.
+// A failed migration stops the run; running it again resumes the failed migration from its last checkpoint.
+func (m *Migrator) Run(ctx context.Context) (*Result, error) {
+	result := &Result{DryRun: m.opts.DryRun}
+
+	if m.opts.DryRun {
+		records, err := m.History(ctx)
+		if err != nil {
+			return result, err
+		}
+		return result, m.runPending(ctx, nil, nil, records, result)
+	}
+
+	history, err := common.CreateContainerIfNotExistsCtx(ctx, m.db, azcosmos.ContainerProperties{
+		ID:                     m.opts.HistoryContainer,
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/id"}},
+	}, nil)
+	if err != nil {
+		return result, err
+	}
+	l, err := acquireLock(ctx, m.db.ID(), history, m.opts)
+	if err != nil {
+		return result, err
+	}
+	defer l.release(context.WithoutCancel(ctx))
+	runCtx, stop := l.keepAlive(ctx)
+	defer stop()
+
+	// read the history only once the lock is held, another instance may just have applied migrations
+	records, err := m.readHistory(runCtx, history)
+	if err == nil {
+		err = m.runPending(runCtx, history, l, records, result)
+	}
+	if err != nil && ctx.Err() == nil {
+		// the migration was interrupted because the lock could not be renewed
+		if cause := context.Cause(runCtx); cause != nil && !errors.Is(err, cause) {
+			err = fmt.Errorf("%w: %w", cause, err)
+		}
+	}
+	return result, err
+}
+
+func (m *Migrator) runPending(ctx context.Context, history *azcosmos.ContainerClient, l *lock, records []Record, result *Result) error {
+	pending, running, err := m.pending(records)
+	if err != nil {
+		return err
+	}
+	t := newThrottle(m.opts.MaxRequestUnits)
+	for _, migration := range pending {
+		record, ok := running[migration.Version]
+		if !ok {
+			record = Record{
+				ID:          recordID(migration.Version),
+				Type:        "migration",
+				Version:     migration.Version,
+				Description: migration.Description,
+				Status:      statusRunning,
+				StartedAt:   time.Now().UTC(),
+			}
+		}
+		res, err := m.apply(ctx, history, l, t, migration, record)
+		result.Migrations = append(result.Migrations, res)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+	}
+	return nil
+}
+
+// pending returns the migrations that have not completed, and the records of those that were interrupted.
+// Migrations older than an applied one are refused, since they would run against documents of a newer shape.
+func (m *Migrator) pending(records []Record) ([]Migration, map[int]Record, error) {
+	completed := map[int]bool{}
+	running := map[int]Record{}
+	latest := 0
+	for _, record := range records {
+		if record.Status == statusCompleted {
+			completed[record.Version] = true
+			latest = max(latest, record.Version)
+		} else {
+			running[record.Version] = record
+		}
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if completed[migration.Version] {
+			continue
+		}
+		if migration.Version < latest {
+			return nil, nil, fmt.Errorf("migration %d is pending but migration %d has already been applied", migration.Version, latest)
+		}
+		pending = append(pending, migration)
+	}
+	return pending, running, nil
+}
+
+// apply runs a migration from the checkpoint in record. history and l are nil in a dry run.
+func (m *Migrator) apply(ctx context.Context, history *azcosmos.ContainerClient, l *lock, t *throttle, migration Migration, record Record) (MigrationResult, error) {
+	res := MigrationResult{Version: migration.Version, Description: migration.Description, Resumed: record.Continuation != ""}
+
+	container, err := m.db.NewContainer(migration.Container)
+	if err != nil {
+		return res, err
+	}
+	resp, err := retry.DoValue(ctx, m.opts.Retry, func(ctx context.Context) (azcosmos.ContainerResponse, error) {
+		return container.Read(ctx, nil)
+	})
+	if err != nil {
+		return res, operationError("ReadContainer", m.db.ID(), container.ID(), "", err)
+	}
+	pkDef := resp.ContainerProperties.PartitionKeyDefinition
+
+	if history != nil {
+		if err := m.checkpoint(ctx, history, record); err != nil {
+			return res, err
+		}
+	}
+
+	query := cmp.Or(migration.Query, defaultQuery)
+	continuation := record.Continuation
+	for {
+		page, err := operations.ExecuteQueryPageCtx[json.RawMessage](ctx, container, query, azcosmos.NewPartitionKey(), m.opts.PageSize, continuation, nil, operations.WithRetry(m.opts.Retry))
+		if err != nil {
+			return res, err
+		}
+		res.RequestCharge += page.RequestCharge
+		if err := t.spend(ctx, page.RequestCharge); err != nil {
+			return res, err
+		}
+
+		for _, raw := range page.Items {
+			res.Scanned++
+			doc, err := decodeDocument(raw)
+			if err != nil {
+				return res, err
+			}
+			changed, charge, err := m.migrateDocument(ctx, container, pkDef, migration, doc)
+			res.RequestCharge += charge
+			if err != nil {
+				return res, err
+			}
+			if changed {
+				res.Migrated++
+			}
+			if err := t.spend(ctx, charge); err != nil {
+				return res, err
+			}
+		}
+
+		continuation = page.ContinuationToken
+		if history != nil {
+			if err := l.renew(ctx); err != nil {
+				return res, err
+			}
+			checkpoint := progress(record, res)
+			checkpoint.Continuation = continuation
+			if continuation == "" {
+				completedAt := time.Now().UTC()
+				checkpoint.Status = statusCompleted
+				checkpoint.CompletedAt = &completedAt
+			}
+			if err := m.checkpoint(ctx, history, checkpoint); err != nil {
+				return res, err
+			}
+		}
+		if continuation == "" {
+			return res, nil
+		}
+	}
+}
+
+// progress adds the counts of a run to the counts of the earlier, interrupted runs in record.
+func progress(record Record, res MigrationResult) Record {
+	record.Scanned += res.Scanned
+	record.Migrated += res.Migrated
+	record.RequestCharge += res.RequestCharge
+	return record
+}
+
+// migrateDocument applies a migration to a document and replaces it if it changed. If the document was modified
+// concurrently, it is read again and migrated again. It returns the RU charge of the reads and replaces.
+func (m *Migrator) migrateDocument(ctx context.Context, container *azcosmos.ContainerClient, pkDef azcosmos.PartitionKeyDefinition, migration Migration, doc map[string]any) (bool, float64, error) {
+	id, _ := doc["id"].(string)
+	pk, err := operations.PartitionKeyFromDocument(doc, pkDef)
+	if err != nil {
+		return false, 0, fmt.Errorf("document %q: %w", id, err)
+	}
+
+	var charge float64
+	for {
+		etag, _ := doc["_etag"].(string)
+		migrated, changed, err := migration.apply(ctx, doc)
+		if err != nil || !changed || m.opts.DryRun {
+			return changed, charge, err
+		}
+		body, err := json.Marshal(migrated)
+		if err != nil {
+			return false, charge, fmt.Errorf("document %q: %w", id, err)
+		}
+
+		opts := &azcosmos.ItemOptions{}
+		if etag != "" {
+			opts.IfMatchEtag = (*azcore.ETag)(&etag)
+		}
+		resp, err := retry.DoValue(ctx, m.opts.Retry, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+			return container.ReplaceItem(ctx, pk, id, body, opts)
+		})
+		charge += float64(resp.RequestCharge)
+		if err == nil {
+			return true, charge, nil
+		}
+		if !errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrPreconditionFailed) {
+			return false, charge, operationError("ReplaceItem", m.db.ID(), container.ID(), id, err)
+		}
+
+		// the document was changed since it was queried, migrate its current version
+		resp, err = retry.DoValue(ctx, m.opts.Retry, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+			return container.ReadItem(ctx, pk, id, nil)
+		})
+		charge += float64(resp.RequestCharge)
+		if err != nil {
+			if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+				return false, charge, nil
+			}
+			return false, charge, operationError("ReadItem", m.db.ID(), container.ID(), id, err)
+		}
+		if doc, err = decodeDocument(resp.Value); err != nil {
+			return false, charge, fmt.Errorf("document %q: %w", id, err)
+		}
+	}
+}
+
+func (m *Migrator) readHistory(ctx context.Context, history *azcosmos.ContainerClient) ([]Record, error) {
+	var records []Record
+	opts := &azcosmos.QueryOptions{QueryParameters: []azcosmos.QueryParameter{{Name: "@type", Value: "migration"}}}
+	for record, err := range operations.QueryIterCtx[Record](ctx, history, "SELECT * FROM c WHERE c.type = @type", azcosmos.NewPartitionKey(), opts, operations.WithRetry(m.opts.Retry)) {
+		if err != nil {
+			return nil, err
+		}
+		if record.Type == "migration" {
+			records = append(records, record)
+		}
+	}
+	slices.SortFunc(records, func(a, b Record) int { return a.Version - b.Version })
+	return records, nil
+}
+
+func (m *Migrator) checkpoint(ctx context.Context, history *azcosmos.ContainerClient, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = retry.DoValue(ctx, m.opts.Retry, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return history.UpsertItem(ctx, azcosmos.NewPartitionKeyString(record.ID), body, nil)
+	})
+	if err != nil {
+		return operationError("UpsertItem", m.db.ID(), history.ID(), record.ID, err)
+	}
+	return nil
+}
+
+func recordID(version int) string {
+	return fmt.Sprintf("v%d", version)
+}
+
+// convert converts between JSON-compatible values through their JSON encoding.
+// Numbers decoded into interface values are json.Number, see decode.
+func convert(from, to any) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return decode(b, to)
+}
+
+// decodeDocument decodes a document into a map. Numbers are kept as json.Number, so that they are written back
+// unchanged: a float64 would round integers above 2^53.
+func decodeDocument(b []byte) (map[string]any, error) {
+	var doc map[string]any
+	if err := decode(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decode unmarshals b into v, decoding numbers into interface values as json.Number.
+func decode(b []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// operationError wraps err in a cosmosdb_errors.OperationError.
+func operationError(operation, database, container, itemID string, err error) error {
+	return cosmosdb_errors.NewOperationError(cosmosdb_errors.OperationError{
+		Operation: operation,
+		Database:  database,
+		Container: container,
+		ItemID:    itemID,
+		Err:       err,
+	})
+}
@@ -0,0 +1,346 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type order struct {
+	ID         string  `json:"id"`
+	CustomerID string  `json:"customerId"`
+	Total      float64 `json:"total"`
+	TotalCents int     `json:"totalCents,omitempty"`
+	Status     string  `json:"status,omitempty"`
+}
+
+// addStatus sets a status on orders that have none.
+var addStatus = Map(1, "add status", "orders", func(_ context.Context, doc map[string]any) (bool, error) {
+	if _, ok := doc["status"]; ok {
+		return false, nil
+	}
+	doc["status"] = "open"
+	return true, nil
+})
+
+// totalInCents adds the total in integer cents.
+var totalInCents = Typed(2, "total in cents", "orders", func(_ context.Context, doc *order) (bool, error) {
+	if doc.TotalCents != 0 {
+		return false, nil
+	}
+	doc.TotalCents = int(doc.Total * 100)
+	return true, nil
+})
+
+func newTestDatabase(t *testing.T, orders int) (*cosmostest.Account, *azcosmos.DatabaseClient) {
+	t.Helper()
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	ctx := context.Background()
+	_, err := client.CreateDatabase(ctx, azcosmos.DatabaseProperties{ID: "shop"}, nil)
+	require.NoError(t, err)
+	db, err := client.NewDatabase("shop")
+	require.NoError(t, err)
+	_, err = db.CreateContainer(ctx, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+	}, nil)
+	require.NoError(t, err)
+	container, err := db.NewContainer("orders")
+	require.NoError(t, err)
+	for i := range orders {
+		body, err := json.Marshal(order{ID: string(rune('a' + i)), CustomerID: "c1", Total: 1.5})
+		require.NoError(t, err)
+		_, err = container.CreateItem(ctx, azcosmos.NewPartitionKeyString("c1"), body, nil)
+		require.NoError(t, err)
+	}
+	return account, db
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(nil, []Migration{
+		addStatus,
+		Map(1, "again", "orders", nil),
+		Typed[order](3, "typed", "orders", nil),
+		{Version: 0, Description: "bare"},
+	}, nil)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "migration 1 is declared twice")
+	assert.ErrorContains(t, err, "migration 1: migration function is nil")
+	assert.ErrorContains(t, err, "migration 3: migration function is nil")
+	assert.ErrorContains(t, err, `migration "bare": version must be positive`)
+	assert.ErrorContains(t, err, "migration 0: container is required")
+	assert.ErrorContains(t, err, "migration 0: create migrations with Map or Typed")
+}
+
+func TestRun(t *testing.T) {
+	account, db := newTestDatabase(t, 5)
+	ctx := context.Background()
+	migrator, err := New(db, []Migration{totalInCents, addStatus}, &Options{PageSize: 2})
+	require.NoError(t, err)
+
+	pending, err := migrator.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	result, err := migrator.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, result.Migrations, 2)
+	assert.Equal(t, 1, result.Migrations[0].Version)
+	assert.Equal(t, 5, result.Migrations[0].Migrated)
+	assert.Equal(t, 2, result.Migrations[1].Version)
+	assert.Equal(t, 5, result.Migrations[1].Scanned)
+	assert.Equal(t, 5, result.Migrations[1].Migrated)
+
+	for _, item := range account.Items("shop", "orders") {
+		assert.Equal(t, "open", item["status"])
+		assert.Equal(t, float64(150), item["totalCents"])
+	}
+
+	history, err := migrator.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, statusCompleted, history[0].Status)
+	assert.Equal(t, "add status", history[0].Description)
+	assert.NotNil(t, history[1].CompletedAt)
+	// the lock is released
+	assert.Len(t, account.Items("shop", "migrations"), 2)
+
+	// applied migrations are not run again
+	result, err = migrator.Run(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.Migrations)
+}
+
+func TestRun_LargeIntegers(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	ctx := context.Background()
+	_, err := client.CreateDatabase(ctx, azcosmos.DatabaseProperties{ID: "shop"}, nil)
+	require.NoError(t, err)
+	db, err := client.NewDatabase("shop")
+	require.NoError(t, err)
+	_, err = db.CreateContainer(ctx, azcosmos.ContainerProperties{
+		ID:                     "orders",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}},
+	}, nil)
+	require.NoError(t, err)
+	container, err := db.NewContainer("orders")
+	require.NoError(t, err)
+	_, err = container.CreateItem(ctx, azcosmos.NewPartitionKeyString("c1"), []byte(`{"id":"a","customerId":"c1","total":1.5,"seq":9007199254740993}`), nil)
+	require.NoError(t, err)
+
+	type sequenced struct {
+		order
+		Seq any `json:"seq"`
+	}
+	inCents := Typed(2, "total in cents", "orders", func(_ context.Context, doc *sequenced) (bool, error) {
+		doc.TotalCents = int(doc.Total * 100)
+		return true, nil
+	})
+	migrator, err := New(db, []Migration{addStatus, inCents}, nil)
+	require.NoError(t, err)
+	_, err = migrator.Run(ctx)
+	require.NoError(t, err)
+
+	resp, err := container.ReadItem(ctx, azcosmos.NewPartitionKeyString("c1"), "a", nil)
+	require.NoError(t, err)
+	// numbers that the migrations don't change are written back exactly
+	assert.Contains(t, string(resp.Value), `"seq":9007199254740993`)
+	assert.Contains(t, string(resp.Value), `"status":"open"`)
+	assert.Contains(t, string(resp.Value), `"totalCents":150`)
+}
+
+func TestRun_OutOfOrder(t *testing.T) {
+	_, db := newTestDatabase(t, 1)
+	ctx := context.Background()
+	migrator, err := New(db, []Migration{totalInCents}, nil)
+	require.NoError(t, err)
+	_, err = migrator.Run(ctx)
+	require.NoError(t, err)
+
+	migrator, err = New(db, []Migration{addStatus, totalInCents}, nil)
+	require.NoError(t, err)
+	_, err = migrator.Run(ctx)
+	assert.EqualError(t, err, "migration 1 is pending but migration 2 has already been applied")
+}
+
+func TestRun_DryRun(t *testing.T) {
+	account, db := newTestDatabase(t, 3)
+	writes := len(account.Writes())
+	migrator, err := New(db, []Migration{addStatus}, &Options{DryRun: true})
+	require.NoError(t, err)
+
+	result, err := migrator.Run(context.Background())
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []MigrationResult{{Version: 1, Description: "add status", Scanned: 3, Migrated: 3}}, result.Migrations)
+	assert.Len(t, account.Writes(), writes)
+	assert.NotContains(t, account.Items("shop", "orders")[0], "status")
+}
+
+func TestRun_Resume(t *testing.T) {
+	account, db := newTestDatabase(t, 5)
+	ctx := context.Background()
+	fail := errors.New("boom")
+	calls := 0
+	flaky := Map(1, "flaky", "orders", func(ctx context.Context, doc map[string]any) (bool, error) {
+		if calls++; calls == 4 {
+			return false, fail
+		}
+		_, changed, err := addStatus.apply(ctx, doc)
+		return changed, err
+	})
+	migrator, err := New(db, []Migration{flaky}, &Options{PageSize: 2})
+	require.NoError(t, err)
+
+	_, err = migrator.Run(ctx)
+	assert.ErrorIs(t, err, fail)
+	history, err := migrator.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, statusRunning, history[0].Status)
+	assert.Equal(t, "2", history[0].Continuation)
+	assert.Equal(t, 2, history[0].Scanned)
+
+	// the second run resumes after the first page, and transforms the interrupted page again
+	result, err := migrator.Run(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Migrations[0].Resumed)
+	assert.Equal(t, 3, result.Migrations[0].Scanned)
+	assert.Equal(t, 2, result.Migrations[0].Migrated)
+	history, err = migrator.History(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, statusCompleted, history[0].Status)
+	assert.Equal(t, 5, history[0].Scanned)
+	assert.Equal(t, 4, history[0].Migrated)
+	for _, item := range account.Items("shop", "orders") {
+		assert.Equal(t, "open", item["status"])
+	}
+}
+
+func TestRun_ConcurrentUpdate(t *testing.T) {
+	account, db := newTestDatabase(t, 1)
+	ctx := context.Background()
+	container, err := db.NewContainer("orders")
+	require.NoError(t, err)
+	calls := 0
+	racy := Map(1, "racy", "orders", func(ctx context.Context, doc map[string]any) (bool, error) {
+		if calls++; calls == 1 {
+			// the application updates the order while it is migrated
+			_, err := container.ReplaceItem(ctx, azcosmos.NewPartitionKeyString("c1"), "a", []byte(`{"id":"a","customerId":"c1","total":2}`), nil)
+			require.NoError(t, err)
+		}
+		doc["status"] = "open"
+		return true, nil
+	})
+	migrator, err := New(db, []Migration{racy}, nil)
+	require.NoError(t, err)
+
+	_, err = migrator.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	item := account.Items("shop", "orders")[0]
+	assert.Equal(t, float64(2), item["total"])
+	assert.Equal(t, "open", item["status"])
+}
+
+func TestRun_Lock(t *testing.T) {
+	account, db := newTestDatabase(t, 1)
+	ctx := context.Background()
+	migrator, err := New(db, []Migration{addStatus}, &Options{Owner: "a"})
+	require.NoError(t, err)
+	_, err = migrator.Run(ctx)
+	require.NoError(t, err)
+
+	history, err := db.NewContainer("migrations")
+	require.NoError(t, err)
+	holder, err := json.Marshal(lockDocument{ID: lockID, Type: "lock", Owner: "b", ExpiresAt: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	_, err = history.CreateItem(ctx, azcosmos.NewPartitionKeyString(lockID), holder, nil)
+	require.NoError(t, err)
+
+	migrator, err = New(db, []Migration{addStatus, totalInCents}, &Options{Owner: "a"})
+	require.NoError(t, err)
+	_, err = migrator.Run(ctx)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.ErrorContains(t, err, "held by b until")
+
+	// an expired lock is taken over
+	holder, err = json.Marshal(lockDocument{ID: lockID, Type: "lock", Owner: "b", ExpiresAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	_, err = history.ReplaceItem(ctx, azcosmos.NewPartitionKeyString(lockID), lockID, holder, nil)
+	require.NoError(t, err)
+	result, err := migrator.Run(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result.Migrations, 1)
+	assert.Len(t, account.Items("shop", "migrations"), 2)
+}
+
+func TestRun_LockRenewedDuringPage(t *testing.T) {
+	account, db := newTestDatabase(t, 2)
+	slow := Map(1, "slow", "orders", func(_ context.Context, doc map[string]any) (bool, error) {
+		time.Sleep(60 * time.Millisecond)
+		return false, nil
+	})
+	migrator, err := New(db, []Migration{slow}, &Options{LockTTL: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	_, err = migrator.Run(context.Background())
+	require.NoError(t, err)
+	renewals := 0
+	for _, request := range account.Writes() {
+		if request == "PUT /dbs/shop/colls/migrations/docs/lock" {
+			renewals++
+		}
+	}
+	// the single page takes longer than the TTL: the lock is renewed while it is migrated, not only after it
+	assert.Greater(t, renewals, 1)
+}
+
+func TestRun_LockLost(t *testing.T) {
+	_, db := newTestDatabase(t, 1)
+	history, err := db.NewContainer("migrations")
+	require.NoError(t, err)
+	stolen := Map(1, "stolen", "orders", func(ctx context.Context, doc map[string]any) (bool, error) {
+		// another instance takes over the lock
+		holder, err := json.Marshal(lockDocument{ID: lockID, Type: "lock", Owner: "b", ExpiresAt: time.Now().Add(time.Minute)})
+		require.NoError(t, err)
+		_, err = history.ReplaceItem(ctx, azcosmos.NewPartitionKeyString(lockID), lockID, holder, nil)
+		require.NoError(t, err)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(time.Second):
+			return false, errors.New("migration was not interrupted")
+		}
+	})
+	migrator, err := New(db, []Migration{stolen}, &Options{Owner: "a", LockTTL: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	_, err = migrator.Run(context.Background())
+	assert.ErrorIs(t, err, ErrLockLost)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	unlimited := newThrottle(0)
+	require.NoError(t, unlimited.spend(ctx, 1e6))
+
+	throttle := newThrottle(1000)
+	start := time.Now()
+	require.NoError(t, throttle.spend(ctx, 50))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, throttle.spend(cancelled, 1000), context.Canceled)
+}
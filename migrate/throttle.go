@@ -0,0 +1,37 @@
+package migrate
+
+import (
+	"context"
+	"time"
+)
+
+// throttle limits the request units spent per second. It keeps the average rate since it was created
+// below the budget, so an expensive request is followed by a proportionally longer pause.
+type throttle struct {
+	perSecond float64
+	start     time.Time
+	spent     float64
+}
+
+// newThrottle returns a throttle for a budget of perSecond RU/s. Zero or less means no limit.
+func newThrottle(perSecond float64) *throttle {
+	return &throttle{perSecond: perSecond, start: time.Now()}
+}
+
+// spend records the charge of a request and waits until the average rate is within the budget again.
+func (t *throttle) spend(ctx context.Context, charge float64) error {
+	if t.perSecond <= 0 {
+		return nil
+	}
+	t.spent += charge
+	ahead := time.Duration(t.spent/t.perSecond*float64(time.Second)) - time.Since(t.start)
+	if ahead <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(ahead):
+		return nil
+	}
+}
@@ -0,0 +1,217 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// MaxBatchOperations is the maximum number of operations Cosmos DB accepts in a single transactional batch.
+const MaxBatchOperations = 100
+
+// BatchOperationType identifies the kind of an operation in a Batch.
+type BatchOperationType string
+
+const (
+	BatchCreate  BatchOperationType = "Create"
+	BatchUpsert  BatchOperationType = "Upsert"
+	BatchReplace BatchOperationType = "Replace"
+	BatchDelete  BatchOperationType = "Delete"
+	BatchRead    BatchOperationType = "Read"
+	BatchPatch   BatchOperationType = "Patch"
+)
+
+type batchOperation struct {
+	kind  BatchOperationType
+	id    string
+	body  []byte
+	patch azcosmos.PatchOperations
+	opts  *azcosmos.TransactionalBatchItemOptions
+}
+
+// Batch builds a transactional batch of operations on items of type T that share a partition key.
+// All operations are committed atomically: if any of them fails, none are applied.
+//
+// Errors are collected while building and returned by Execute.
+type Batch[T any] struct {
+	partitionKey azcosmos.PartitionKey
+	operations   []batchOperation
+	errs         []error
+}
+
+// BatchOperationResult is the outcome of a single operation in a batch.
+type BatchOperationResult[T any] struct {
+	Type          BatchOperationType
+	StatusCode    int
+	RequestCharge float64
+	ETag          azcore.ETag
+	// Item is the item returned by the operation. It is the zero value for deletes and for failed operations.
+	Item T
+}
+
+// BatchResult is the outcome of a transactional batch.
+type BatchResult[T any] struct {
+	// Results holds one entry per operation, in the order the operations were added.
+	Results       []BatchOperationResult[T]
+	RequestCharge float64
+	// Success is true if the batch was committed.
+	Success bool
+}
+
+// BatchError is returned by Batch.Execute when the batch was rolled back because one of its operations failed.
+type BatchError struct {
+	// Index is the position of the operation that caused the rollback.
+	Index      int
+	Type       BatchOperationType
+	StatusCode int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("transactional batch rolled back: operation %d (%s) failed with status %d", e.Index, e.Type, e.StatusCode)
+}
+
+// NewBatch returns an empty Batch for items of type T in the given partition.
+func NewBatch[T any](partitionKey azcosmos.PartitionKey) *Batch[T] {
+	return &Batch[T]{partitionKey: partitionKey}
+}
+
+// Len returns the number of operations in the batch.
+func (b *Batch[T]) Len() int {
+	return len(b.operations)
+}
+
+// Create adds an operation that creates item.
+func (b *Batch[T]) Create(item T, opts *azcosmos.TransactionalBatchItemOptions) *Batch[T] {
+	return b.addItem(BatchCreate, "", item, opts)
+}
+
+// Upsert adds an operation that creates or replaces item.
+func (b *Batch[T]) Upsert(item T, opts *azcosmos.TransactionalBatchItemOptions) *Batch[T] {
+	return b.addItem(BatchUpsert, "", item, opts)
+}
+
+// Replace adds an operation that replaces the item with the given id.
+func (b *Batch[T]) Replace(itemID string, item T, opts *azcosmos.TransactionalBatchItemOptions) *Batch[T] {
+	return b.addItem(BatchReplace, itemID, item, opts)
+}
+
+// Delete adds an operation that deletes the item with the given id.
+func (b *Batch[T]) Delete(itemID string, opts *azcosmos.TransactionalBatchItemOptions) *Batch[T] {
+	b.operations = append(b.operations, batchOperation{kind: BatchDelete, id: itemID, opts: opts})
+	return b
+}
+
+// Read adds an operation that reads the item with the given id.
+func (b *Batch[T]) Read(itemID string, opts *azcosmos.TransactionalBatchItemOptions) *Batch[T] {
+	b.operations = append(b.operations, batchOperation{kind: BatchRead, id: itemID, opts: opts})
+	return b
+}
+
+// Patch adds an operation that patches the item with the given id.
+func (b *Batch[T]) Patch(itemID string, patch *PatchBuilder[T], opts *azcosmos.TransactionalBatchItemOptions) *Batch[T] {
+	ops, err := patch.Build()
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("batch operation %d: %w", len(b.operations), err))
+	}
+	b.operations = append(b.operations, batchOperation{kind: BatchPatch, id: itemID, patch: ops, opts: opts})
+	return b
+}
+
+func (b *Batch[T]) addItem(kind BatchOperationType, itemID string, item T, opts *azcosmos.TransactionalBatchItemOptions) *Batch[T] {
+	body, err := json.Marshal(item)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("batch operation %d: %w", len(b.operations), err))
+	}
+	b.operations = append(b.operations, batchOperation{kind: kind, id: itemID, body: body, opts: opts})
+	return b
+}
+
+// Execute validates the batch and commits it. Responses of write operations are always returned and decoded as T.
+// If the batch is rolled back, the BatchResult is returned along with a *BatchError identifying the failing operation.
+func (b *Batch[T]) Execute(container *azcosmos.ContainerClient, opts *azcosmos.TransactionalBatchOptions, callOpts ...CallOption) (BatchResult[T], error) {
+	return b.ExecuteCtx(context.Background(), container, opts, callOpts...)
+}
+
+// ExecuteCtx is like Execute but uses the provided context for the Cosmos DB call.
+func (b *Batch[T]) ExecuteCtx(ctx context.Context, container *azcosmos.ContainerClient, opts *azcosmos.TransactionalBatchOptions, callOpts ...CallOption) (BatchResult[T], error) {
+	if len(b.errs) > 0 {
+		return BatchResult[T]{}, errors.Join(b.errs...)
+	}
+	if len(b.operations) == 0 {
+		return BatchResult[T]{}, errors.New("batch has no operations")
+	}
+	if len(b.operations) > MaxBatchOperations {
+		return BatchResult[T]{}, fmt.Errorf("batch has %d operations, maximum is %d", len(b.operations), MaxBatchOperations)
+	}
+
+	batch := container.NewTransactionalBatch(b.partitionKey)
+	for _, op := range b.operations {
+		switch op.kind {
+		case BatchCreate:
+			batch.CreateItem(op.body, op.opts)
+		case BatchUpsert:
+			batch.UpsertItem(op.body, op.opts)
+		case BatchReplace:
+			batch.ReplaceItem(op.id, op.body, op.opts)
+		case BatchDelete:
+			batch.DeleteItem(op.id, op.opts)
+		case BatchRead:
+			batch.ReadItem(op.id, op.opts)
+		case BatchPatch:
+			batch.PatchItem(op.id, op.patch, op.opts)
+		}
+	}
+
+	batchOpts := azcosmos.TransactionalBatchOptions{}
+	if opts != nil {
+		batchOpts = *opts
+	}
+	batchOpts.EnableContentResponseOnWrite = true
+
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.TransactionalBatchResponse, error) {
+		return container.ExecuteTransactionalBatch(ctx, batch, &batchOpts)
+	})
+	if err != nil {
+		return BatchResult[T]{}, operationError("ExecuteTransactionalBatch", container, "", err)
+	}
+
+	result := BatchResult[T]{
+		Results:       make([]BatchOperationResult[T], 0, len(response.OperationResults)),
+		RequestCharge: float64(response.RequestCharge),
+		Success:       response.Success,
+	}
+	var batchErr *BatchError
+	for i, opResult := range response.OperationResults {
+		r := BatchOperationResult[T]{
+			StatusCode:    int(opResult.StatusCode),
+			RequestCharge: float64(opResult.RequestCharge),
+			ETag:          opResult.ETag,
+		}
+		if i < len(b.operations) {
+			r.Type = b.operations[i].kind
+		}
+		if opResult.StatusCode >= 200 && opResult.StatusCode < 300 && len(opResult.ResourceBody) > 0 {
+			if err := json.Unmarshal(opResult.ResourceBody, &r.Item); err != nil {
+				return result, fmt.Errorf("batch operation %d: %w", i, err)
+			}
+		}
+		// the cause of a rollback is the first operation that did not fail with a dependency failure
+		if !response.Success && batchErr == nil && r.StatusCode != http.StatusFailedDependency && (r.StatusCode < 200 || r.StatusCode >= 300) {
+			batchErr = &BatchError{Index: i, Type: r.Type, StatusCode: r.StatusCode}
+		}
+		result.Results = append(result.Results, r)
+	}
+
+	if !response.Success {
+		if batchErr == nil {
+			batchErr = &BatchError{Index: -1, StatusCode: response.RawResponse.StatusCode}
+		}
+		return result, batchErr
+	}
+	return result, nil
+}
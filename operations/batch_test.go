@@ -0,0 +1,104 @@
+package operations
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchOperationRequest struct {
+	OperationType string          `json:"operationType"`
+	ID            string          `json:"id"`
+	ResourceBody  json.RawMessage `json:"resourceBody"`
+}
+
+// batchHandler records the operations of a batch request and answers with results and status.
+func batchHandler(t *testing.T, received *[]batchOperationRequest, status int, results []map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/dbs/db/colls/container/docs", r.URL.Path)
+		assert.Equal(t, `["p1"]`, r.Header.Get("x-ms-documentdb-partitionkey"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(received))
+		w.Header().Set("x-ms-request-charge", "12.5")
+		cosmostest.WriteJSON(w, status, results)
+	}
+}
+
+func TestBatch_Execute(t *testing.T) {
+	var received []batchOperationRequest
+	container := cosmostest.NewContainer(t, batchHandler(t, &received, http.StatusOK, []map[string]any{
+		{"statusCode": 201, "requestCharge": 5.5, "eTag": "e1", "resourceBody": testItem{ID: "1", Name: "a"}},
+		{"statusCode": 200, "requestCharge": 1, "resourceBody": testItem{ID: "2", Name: "b", Count: 3}},
+		{"statusCode": 204, "requestCharge": 6},
+	}))
+
+	result, err := NewBatch[testItem](azcosmos.NewPartitionKeyString("p1")).
+		Create(testItem{ID: "1", Name: "a"}, nil).
+		Read("2", nil).
+		Delete("3", nil).
+		Execute(container, nil)
+	require.NoError(t, err)
+
+	require.Len(t, received, 3)
+	assert.Equal(t, "Create", received[0].OperationType)
+	assert.JSONEq(t, `{"id":"1","name":"a","count":0}`, string(received[0].ResourceBody))
+	assert.Equal(t, "Read", received[1].OperationType)
+	assert.Equal(t, "2", received[1].ID)
+	assert.Equal(t, "Delete", received[2].OperationType)
+
+	assert.True(t, result.Success)
+	assert.Equal(t, 12.5, result.RequestCharge)
+	require.Len(t, result.Results, 3)
+	assert.Equal(t, BatchCreate, result.Results[0].Type)
+	assert.Equal(t, http.StatusCreated, result.Results[0].StatusCode)
+	assert.Equal(t, "e1", string(result.Results[0].ETag))
+	assert.Equal(t, "a", result.Results[0].Item.Name)
+	assert.Equal(t, 3, result.Results[1].Item.Count)
+	assert.Equal(t, BatchDelete, result.Results[2].Type)
+	assert.Zero(t, result.Results[2].Item)
+}
+
+func TestBatch_Execute_RolledBack(t *testing.T) {
+	var received []batchOperationRequest
+	container := cosmostest.NewContainer(t, batchHandler(t, &received, http.StatusMultiStatus, []map[string]any{
+		{"statusCode": 424},
+		{"statusCode": 409},
+		{"statusCode": 424},
+	}))
+
+	result, err := NewBatch[testItem](azcosmos.NewPartitionKeyString("p1")).
+		Upsert(testItem{ID: "1"}, nil).
+		Create(testItem{ID: "2"}, nil).
+		Replace("3", testItem{ID: "3"}, nil).
+		Execute(container, nil)
+
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 1, batchErr.Index)
+	assert.Equal(t, BatchCreate, batchErr.Type)
+	assert.Equal(t, http.StatusConflict, batchErr.StatusCode)
+	assert.False(t, result.Success)
+	assert.Len(t, result.Results, 3)
+}
+
+func TestBatch_Execute_Validation(t *testing.T) {
+	container := cosmostest.NewContainer(t, cosmostest.Unexpected(t))
+	pk := azcosmos.NewPartitionKeyString("p1")
+
+	_, err := NewBatch[testItem](pk).Execute(container, nil)
+	assert.ErrorContains(t, err, "no operations")
+
+	batch := NewBatch[testItem](pk)
+	for range MaxBatchOperations + 1 {
+		batch.Delete("1", nil)
+	}
+	_, err = batch.Execute(container, nil)
+	assert.ErrorContains(t, err, "maximum is 100")
+
+	_, err = NewBatch[testItem](pk).Patch("1", NewPatchBuilder[testItem](), nil).Execute(container, nil)
+	assert.ErrorIs(t, err, ErrNoPatchOperations)
+}
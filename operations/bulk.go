@@ -0,0 +1,282 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+const (
+	defaultBulkConcurrency        = 8
+	defaultBulkMaxThrottleRetries = 9
+	defaultBulkThrottleBackoff    = 100 * time.Millisecond
+	maxBulkThrottleBackoff        = 5 * time.Second
+)
+
+// BulkOptions configures BulkUpsert and BulkUpsertChan.
+type BulkOptions[T any] struct {
+	// Concurrency is the number of concurrent writers. Defaults to 8.
+	Concurrency int
+	// PartitionConcurrency is the number of writers that items of the same partition key are spread over,
+	// at most Concurrency. Defaults to 1, which writes the items of a logical partition one at a time, in input order:
+	// a bulk write to a single partition key is then not concurrent. Higher values write a logical partition
+	// concurrently, without ordering guarantees.
+	PartitionConcurrency int
+	// PartitionKey returns the partition key of an item. Defaults to PartitionKeyOf (`cosmos:"pk"` struct tags).
+	PartitionKey func(T) (azcosmos.PartitionKey, error)
+	// MaxThrottleRetries is the number of times a throttled (HTTP 429) write is retried. Defaults to 9.
+	// Use a negative value to disable retries.
+	MaxThrottleRetries int
+	// CreateOnly creates items instead of upserting them, so that existing items are reported as conflicts.
+	CreateOnly bool
+	// ItemOptions are passed to every write.
+	ItemOptions *azcosmos.ItemOptions
+}
+
+// BulkResult is the outcome of writing a single item.
+type BulkResult[T any] struct {
+	// Index is the position of the item in the input.
+	Index int
+	Item  T
+	// RequestCharge is the RU charge of all attempts, including throttled ones.
+	RequestCharge float64
+	// Err is nil if the item was written successfully.
+	Err error
+	// CosmosError holds the Cosmos DB status of a failed write. It is empty if the failure was not a Cosmos DB error.
+	CosmosError cosmosdb_errors.CosmosDBError
+}
+
+// BulkReport summarises a bulk write.
+type BulkReport[T any] struct {
+	// Results holds one entry per input item, in input order.
+	Results       []BulkResult[T]
+	Succeeded     int
+	Failed        int
+	RequestCharge float64
+}
+
+// Failures returns the results of the items that could not be written.
+func (r BulkReport[T]) Failures() []BulkResult[T] {
+	var failures []BulkResult[T]
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failures = append(failures, res)
+		}
+	}
+	return failures
+}
+
+// BulkUpsert writes items concurrently and returns a per-item report.
+// Items are grouped by partition key so that writes to the same logical partition are sent by the same worker, in input order,
+// unless BulkOptions.PartitionConcurrency spreads them over several workers.
+// Throttled writes (HTTP 429) are retried after the server-provided retry-after interval, during which all workers pause.
+// Individual failures do not stop the bulk write; check the report.
+func BulkUpsert[T any](container *azcosmos.ContainerClient, items []T, opts *BulkOptions[T]) BulkReport[T] {
+	return BulkUpsertCtx(context.Background(), container, items, opts)
+}
+
+// BulkUpsertCtx is like BulkUpsert but uses the provided context for all Cosmos DB calls.
+// Items that were not written before the context was cancelled are reported as failed with the context error.
+func BulkUpsertCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, items []T, opts *BulkOptions[T]) BulkReport[T] {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for _, item := range items {
+			ch <- item
+		}
+	}()
+	return BulkUpsertChanCtx(ctx, container, ch, opts)
+}
+
+// BulkUpsertChan is like BulkUpsert but reads items from a channel until it is closed.
+func BulkUpsertChan[T any](container *azcosmos.ContainerClient, items <-chan T, opts *BulkOptions[T]) BulkReport[T] {
+	return BulkUpsertChanCtx(context.Background(), container, items, opts)
+}
+
+// BulkUpsertChanCtx is like BulkUpsertChan but uses the provided context for all Cosmos DB calls.
+// The channel is drained even if the context is cancelled, so that the producer is never blocked.
+func BulkUpsertChanCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, items <-chan T, opts *BulkOptions[T]) BulkReport[T] {
+	o := BulkOptions[T]{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultBulkConcurrency
+	}
+	if o.PartitionKey == nil {
+		o.PartitionKey = PartitionKeyOf[T]
+	}
+	if o.MaxThrottleRetries == 0 {
+		o.MaxThrottleRetries = defaultBulkMaxThrottleRetries
+	}
+	o.PartitionConcurrency = min(max(o.PartitionConcurrency, 1), o.Concurrency)
+
+	w := &bulkWriter[T]{ctx: ctx, container: container, opts: o}
+
+	type job struct {
+		index        int
+		item         T
+		partitionKey azcosmos.PartitionKey
+	}
+
+	var (
+		mu      sync.Mutex
+		results []BulkResult[T]
+		wg      sync.WaitGroup
+	)
+	record := func(res BulkResult[T]) {
+		mu.Lock()
+		defer mu.Unlock()
+		for len(results) <= res.Index {
+			results = append(results, BulkResult[T]{})
+		}
+		results[res.Index] = res
+	}
+
+	queues := make([]chan job, o.Concurrency)
+	for i := range queues {
+		queues[i] = make(chan job, 1)
+		wg.Add(1)
+		go func(queue <-chan job) {
+			defer wg.Done()
+			for j := range queue {
+				record(w.write(j.index, j.item, j.partitionKey))
+			}
+		}(queues[i])
+	}
+
+	// next counts the items dispatched per partition key, to spread a partition over its PartitionConcurrency workers
+	next := map[string]int{}
+	index := 0
+	for item := range items {
+		partitionKey, err := o.PartitionKey(item)
+		if err != nil {
+			record(BulkResult[T]{Index: index, Item: item, Err: err})
+			index++
+			continue
+		}
+		// consecutive items of a partition go to the next of its PartitionConcurrency workers
+		shard := partitionShard(partitionKey, o.Concurrency)
+		if o.PartitionConcurrency > 1 {
+			key := fmt.Sprint(partitionKey)
+			shard = (shard + next[key]%o.PartitionConcurrency) % o.Concurrency
+			next[key]++
+		}
+		queues[shard] <- job{index: index, item: item, partitionKey: partitionKey}
+		index++
+	}
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+
+	report := BulkReport[T]{Results: results}
+	for _, res := range results {
+		if res.Err != nil {
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+		report.RequestCharge += res.RequestCharge
+	}
+	return report
+}
+
+type bulkWriter[T any] struct {
+	ctx       context.Context
+	container *azcosmos.ContainerClient
+	opts      BulkOptions[T]
+	// throttledUntil (unix nanoseconds) makes all workers back off together after a 429
+	throttledUntil atomic.Int64
+}
+
+func (w *bulkWriter[T]) write(index int, item T, partitionKey azcosmos.PartitionKey) BulkResult[T] {
+	res := BulkResult[T]{Index: index, Item: item}
+	operation := "UpsertItem"
+	if w.opts.CreateOnly {
+		operation = "CreateItem"
+	}
+	fail := func(err error) BulkResult[T] {
+		res.Err = operationError(operation, w.container, "", err)
+		res.CosmosError = cosmosdb_errors.GetError(err)
+		return res
+	}
+
+	itemBytes, err := json.Marshal(item)
+	if err != nil {
+		return fail(err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := w.waitForThrottle(); err != nil {
+			return fail(err)
+		}
+
+		var response azcosmos.ItemResponse
+		if w.opts.CreateOnly {
+			response, err = w.container.CreateItem(w.ctx, partitionKey, itemBytes, w.opts.ItemOptions)
+		} else {
+			response, err = w.container.UpsertItem(w.ctx, partitionKey, itemBytes, w.opts.ItemOptions)
+		}
+		res.RequestCharge += float64(response.RequestCharge)
+		if err == nil {
+			return res
+		}
+
+		cosmosError := cosmosdb_errors.GetError(err)
+		res.RequestCharge += cosmosError.RequestCharge
+		if cosmosError.Status != http.StatusTooManyRequests {
+			return fail(err)
+		}
+		if attempt >= w.opts.MaxThrottleRetries {
+			return fail(fmt.Errorf("still throttled after %d attempts: %w", attempt+1, err))
+		}
+		backoff := cosmosError.RetryAfter
+		if backoff == 0 {
+			// the shift is capped so that it cannot overflow with large MaxThrottleRetries
+			backoff = min(defaultBulkThrottleBackoff<<min(attempt, 16), maxBulkThrottleBackoff)
+		}
+		w.throttle(backoff)
+	}
+}
+
+func (w *bulkWriter[T]) throttle(d time.Duration) {
+	until := time.Now().Add(d).UnixNano()
+	for {
+		current := w.throttledUntil.Load()
+		if current >= until || w.throttledUntil.CompareAndSwap(current, until) {
+			return
+		}
+	}
+}
+
+func (w *bulkWriter[T]) waitForThrottle() error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	d := time.Until(time.Unix(0, w.throttledUntil.Load()))
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// partitionShard maps a partition key to a worker so that all items of a logical partition are written by the same worker.
+func partitionShard(partitionKey azcosmos.PartitionKey, shards int) int {
+	h := fnv.New32a()
+	fmt.Fprint(h, partitionKey)
+	return int(h.Sum32() % uint32(shards))
+}
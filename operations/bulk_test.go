@@ -0,0 +1,197 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkItem struct {
+	ID     string `json:"id"`
+	Tenant string `json:"tenant" cosmos:"pk"`
+}
+
+// bulkStore accepts writes, throttling the first attempt of selected ids and rejecting others.
+type bulkStore struct {
+	t        *testing.T
+	mu       sync.Mutex
+	attempts map[string]int
+	throttle map[string]bool
+	reject   map[string]int
+	order    map[string][]string
+}
+
+func newBulkStore(t *testing.T) *bulkStore {
+	return &bulkStore{t: t, attempts: map[string]int{}, throttle: map[string]bool{}, reject: map[string]int{}, order: map[string][]string{}}
+}
+
+func (s *bulkStore) handle(w http.ResponseWriter, r *http.Request) {
+	var item bulkItem
+	require.NoError(s.t, json.NewDecoder(r.Body).Decode(&item))
+
+	s.mu.Lock()
+	s.attempts[item.ID]++
+	attempt := s.attempts[item.ID]
+	s.mu.Unlock()
+
+	w.Header().Set("x-ms-request-charge", "5")
+	if s.throttle[item.ID] && attempt == 1 {
+		w.Header().Set("x-ms-retry-after-ms", "10")
+		cosmostest.WriteError(w, http.StatusTooManyRequests, "TooManyRequests", "throttled")
+		return
+	}
+	if status := s.reject[item.ID]; status != 0 {
+		cosmostest.WriteError(w, status, http.StatusText(status), "rejected")
+		return
+	}
+
+	s.mu.Lock()
+	s.order[item.Tenant] = append(s.order[item.Tenant], item.ID)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestBulkUpsert(t *testing.T) {
+	store := newBulkStore(t)
+	store.throttle["3"] = true
+	store.reject["5"] = http.StatusRequestEntityTooLarge
+	container := cosmostest.NewContainer(t, store.handle)
+
+	var items []bulkItem
+	for i := range 10 {
+		items = append(items, bulkItem{ID: strconv.Itoa(i), Tenant: "t" + strconv.Itoa(i%2)})
+	}
+
+	report := BulkUpsert(container, items, &BulkOptions[bulkItem]{Concurrency: 4})
+	require.Len(t, report.Results, 10)
+	assert.Equal(t, 9, report.Succeeded)
+	assert.Equal(t, 1, report.Failed)
+
+	for i, res := range report.Results {
+		assert.Equal(t, i, res.Index)
+		assert.Equal(t, items[i], res.Item)
+	}
+
+	failures := report.Failures()
+	require.Len(t, failures, 1)
+	assert.Equal(t, "5", failures[0].Item.ID)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, failures[0].CosmosError.Status)
+
+	// the throttled item was retried and both attempts were charged
+	assert.Equal(t, 2, store.attempts["3"])
+	assert.Equal(t, 10.0, report.Results[3].RequestCharge)
+	assert.Equal(t, 55.0, report.RequestCharge)
+
+	// items of a partition are written in input order
+	assert.Equal(t, []string{"0", "2", "4", "6", "8"}, store.order["t0"])
+	assert.Equal(t, []string{"1", "3", "7", "9"}, store.order["t1"])
+}
+
+func TestBulkUpsert_PartitionConcurrency(t *testing.T) {
+	var (
+		mu                    sync.Mutex
+		inFlight, maxInFlight int
+	)
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var items []bulkItem
+	for i := range 8 {
+		items = append(items, bulkItem{ID: strconv.Itoa(i), Tenant: "t"})
+	}
+	report := BulkUpsert(container, items, &BulkOptions[bulkItem]{Concurrency: 4, PartitionConcurrency: 4})
+	assert.Equal(t, 8, report.Succeeded)
+	assert.Greater(t, maxInFlight, 1, "a single partition key must be written concurrently")
+
+	maxInFlight = 0
+	report = BulkUpsert(container, items, &BulkOptions[bulkItem]{Concurrency: 4})
+	assert.Equal(t, 8, report.Succeeded)
+	assert.Equal(t, 1, maxInFlight)
+}
+
+func TestBulkUpsert_PartitionConcurrencyInterleaved(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		inFlight    = map[string]int{}
+		maxInFlight = map[string]int{}
+	)
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		pk := r.Header.Get("x-ms-documentdb-partitionkey")
+		mu.Lock()
+		inFlight[pk]++
+		maxInFlight[pk] = max(maxInFlight[pk], inFlight[pk])
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight[pk]--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// a, b, a, b, ...: every partition key must still be spread over its workers
+	var items []bulkItem
+	for i := range 16 {
+		items = append(items, bulkItem{ID: strconv.Itoa(i), Tenant: []string{"a", "b"}[i%2]})
+	}
+	report := BulkUpsert(container, items, &BulkOptions[bulkItem]{Concurrency: 8, PartitionConcurrency: 2})
+	assert.Equal(t, 16, report.Succeeded)
+	assert.Equal(t, map[string]int{`["a"]`: 2, `["b"]`: 2}, maxInFlight)
+}
+
+func TestBulkUpsert_ThrottleRetriesExhausted(t *testing.T) {
+	store := newBulkStore(t)
+	store.throttle["1"] = true
+	container := cosmostest.NewContainer(t, store.handle)
+
+	report := BulkUpsert(container, []bulkItem{{ID: "1", Tenant: "t"}}, &BulkOptions[bulkItem]{MaxThrottleRetries: -1})
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, http.StatusTooManyRequests, report.Results[0].CosmosError.Status)
+}
+
+func TestBulkUpsert_PartitionKeyError(t *testing.T) {
+	container := cosmostest.NewContainer(t, cosmostest.Unexpected(t))
+
+	report := BulkUpsert(container, []map[string]any{{"id": "1"}}, nil)
+	assert.Equal(t, 1, report.Failed)
+	assert.ErrorIs(t, report.Results[0].Err, ErrNoPartitionKeyField)
+}
+
+func TestBulkUpsertChanCtx_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	container := cosmostest.NewContainer(t, cosmostest.Unexpected(t))
+
+	ch := make(chan bulkItem)
+	go func() {
+		defer close(ch)
+		for i := range 5 {
+			ch <- bulkItem{ID: strconv.Itoa(i), Tenant: "t"}
+		}
+	}()
+
+	report := BulkUpsertChanCtx(ctx, container, ch, &BulkOptions[bulkItem]{
+		PartitionKey: func(i bulkItem) (azcosmos.PartitionKey, error) { return azcosmos.NewPartitionKeyString(i.Tenant), nil },
+	})
+	assert.Equal(t, 5, report.Failed)
+	for _, res := range report.Results {
+		assert.ErrorIs(t, res.Err, context.Canceled)
+	}
+}
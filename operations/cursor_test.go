@@ -0,0 +1,86 @@
+package operations
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleScope = "orders/open"
+
+const sampleToken = `{"token":"+RID:~abc==#RT:1#TRC:2#ISV:2#IEO:65567#QCF:8","range":{"min":"","max":"FF"}}`
+
+func TestCursorCodec_Unsigned(t *testing.T) {
+	codec := NewCursorCodec(nil)
+
+	cursor := codec.Encode(sampleScope, sampleToken)
+	assert.Equal(t, url.QueryEscape(cursor), cursor, "cursor must be URL-safe")
+
+	token, err := codec.Decode(sampleScope, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, sampleToken, token)
+}
+
+func TestCursorCodec_Signed(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+
+	cursor := codec.Encode(sampleScope, sampleToken)
+	assert.Equal(t, url.QueryEscape(cursor), cursor, "cursor must be URL-safe")
+
+	token, err := codec.Decode(sampleScope, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, sampleToken, token)
+}
+
+func TestCursorCodec_Empty(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+
+	assert.Empty(t, codec.Encode(sampleScope, ""))
+	token, err := codec.Decode(sampleScope, "")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+func TestCursorCodec_Tampered(t *testing.T) {
+	signed := NewCursorCodec([]byte("secret"))
+	unsigned := NewCursorCodec(nil)
+	cursor := signed.Encode(sampleScope, sampleToken)
+
+	tests := map[string]struct {
+		codec  *CursorCodec
+		cursor string
+	}{
+		"different key":      {NewCursorCodec([]byte("other")), cursor},
+		"missing signature":  {signed, unsigned.Encode(sampleScope, sampleToken)},
+		"modified token":     {signed, unsigned.Encode(sampleScope, sampleToken+"x") + cursor[len(unsigned.Encode(sampleScope, sampleToken)):]},
+		"bad base64":         {signed, "!!!." + cursor},
+		"signed to unsigned": {unsigned, cursor},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := tt.codec.Decode(sampleScope, tt.cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
+
+func TestCursorCodec_Scope(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	scopeA, err := CursorScope("orders", "SELECT * FROM c WHERE c.status = @status", []azcosmos.QueryParameter{{Name: "@status", Value: "open"}})
+	require.NoError(t, err)
+	scopeB, err := CursorScope("orders", "SELECT * FROM c WHERE c.status = @status", []azcosmos.QueryParameter{{Name: "@status", Value: "closed"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, scopeA, scopeB)
+
+	cursor := codec.Encode(scopeA, sampleToken)
+	token, err := codec.Decode(scopeA, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, sampleToken, token)
+
+	// a cursor of query A is rejected for query B
+	_, err = codec.Decode(scopeB, cursor)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
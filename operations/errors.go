@@ -0,0 +1,17 @@
+package operations
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+// operationError wraps err in a cosmosdb_errors.OperationError for an operation on container.
+// The database and partition key are filled in from the failed request.
+func operationError(operation string, container *azcosmos.ContainerClient, itemID string, err error) error {
+	return cosmosdb_errors.NewOperationError(cosmosdb_errors.OperationError{
+		Operation: operation,
+		Container: container.ID(),
+		ItemID:    itemID,
+		Err:       err,
+	})
+}
@@ -0,0 +1,142 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/operations/metrics"
+)
+
+// QueryPage is a single page of query results.
+type QueryPage[T any] struct {
+	Items []T
+	// Metrics is nil if the service did not return query metrics for the page.
+	Metrics       *metrics.QueryMetrics
+	RequestCharge float64
+	// ContinuationToken can be used to resume the query after this page. It is empty on the last page.
+	ContinuationToken string
+}
+
+// QueryIter executes a SQL query against a Cosmos DB container and returns an iterator over strongly typed results.
+// Pages are fetched lazily as the caller ranges over the results; breaking out of the loop stops fetching.
+// If a page cannot be fetched or an item cannot be unmarshaled, the error is yielded and iteration stops.
+func QueryIter[T any](container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) iter.Seq2[T, error] {
+	return QueryIterCtx[T](context.Background(), container, query, partitionKey, opts, callOpts...)
+}
+
+// QueryIterCtx is like QueryIter but uses the provided context for every page request.
+func QueryIterCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range QueryPagesCtx[T](ctx, container, query, partitionKey, opts, callOpts...) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// QueryPages executes a SQL query against a Cosmos DB container and returns an iterator over result pages.
+// Each page carries its items along with the request charge, query metrics and continuation token for that page.
+// Pages are fetched lazily; breaking out of the loop stops fetching.
+func QueryPages[T any](container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) iter.Seq2[QueryPage[T], error] {
+	return QueryPagesCtx[T](context.Background(), container, query, partitionKey, opts, callOpts...)
+}
+
+// QueryPagesCtx is like QueryPages but uses the provided context for every page request.
+func QueryPagesCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) iter.Seq2[QueryPage[T], error] {
+	return func(yield func(QueryPage[T], error) bool) {
+		queryPager := container.NewQueryItemsPager(query, partitionKey, opts)
+
+		for queryPager.More() {
+			if err := ctx.Err(); err != nil {
+				yield(QueryPage[T]{}, operationError("QueryItems", container, "", err))
+				return
+			}
+			queryResponse, err := call(ctx, callOpts, queryPager.NextPage)
+			if err != nil {
+				yield(QueryPage[T]{}, operationError("QueryItems", container, "", err))
+				return
+			}
+
+			page, err := newQueryPage[T](queryResponse)
+			if err != nil {
+				yield(QueryPage[T]{}, operationError("QueryItems", container, "", err))
+				return
+			}
+			if !yield(page, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ExecuteQueryPage fetches a single page of query results, which makes it suitable for API endpoints that page through results.
+// At most maxItemCount items are returned (the service may return fewer). Pass the ContinuationToken of the previous page
+// to resume the query, or an empty string to start from the beginning. The returned page has an empty ContinuationToken
+// when there are no more results. See CursorCodec to hand continuation tokens to clients.
+func ExecuteQueryPage[T any](container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, maxItemCount int32, continuationToken string, opts *azcosmos.QueryOptions, callOpts ...CallOption) (QueryPage[T], error) {
+	return ExecuteQueryPageCtx[T](context.Background(), container, query, partitionKey, maxItemCount, continuationToken, opts, callOpts...)
+}
+
+// ExecuteQueryPageCtx is like ExecuteQueryPage but uses the provided context for the Cosmos DB call.
+func ExecuteQueryPageCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, maxItemCount int32, continuationToken string, opts *azcosmos.QueryOptions, callOpts ...CallOption) (QueryPage[T], error) {
+	pageOpts := azcosmos.QueryOptions{}
+	if opts != nil {
+		pageOpts = *opts
+	}
+	pageOpts.PageSizeHint = maxItemCount
+	pageOpts.ContinuationToken = nil
+	if continuationToken != "" {
+		pageOpts.ContinuationToken = &continuationToken
+	}
+
+	queryPager := container.NewQueryItemsPager(query, partitionKey, &pageOpts)
+	queryResponse, err := call(ctx, callOpts, queryPager.NextPage)
+	if err != nil {
+		return QueryPage[T]{}, operationError("QueryItems", container, "", err)
+	}
+
+	page, err := newQueryPage[T](queryResponse)
+	if err != nil {
+		return QueryPage[T]{}, operationError("QueryItems", container, "", err)
+	}
+	return page, nil
+}
+
+func newQueryPage[T any](queryResponse azcosmos.QueryItemsResponse) (QueryPage[T], error) {
+	page := QueryPage[T]{
+		Items:         make([]T, 0, len(queryResponse.Items)),
+		RequestCharge: float64(queryResponse.RequestCharge),
+	}
+
+	for _, item := range queryResponse.Items {
+		var typedItem T
+		if err := json.Unmarshal(item, &typedItem); err != nil {
+			return QueryPage[T]{}, err
+		}
+		page.Items = append(page.Items, typedItem)
+	}
+
+	if queryResponse.QueryMetrics != nil {
+		qm, err := metrics.ParseQueryMetrics(*queryResponse.QueryMetrics)
+		if err != nil {
+			return QueryPage[T]{}, err
+		}
+		page.Metrics = &qm
+	}
+
+	if queryResponse.ContinuationToken != nil {
+		page.ContinuationToken = *queryResponse.ContinuationToken
+	}
+
+	return page, nil
+}
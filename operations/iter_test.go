@@ -0,0 +1,120 @@
+package operations
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var threePages = [][]testItem{
+	{{ID: "1"}, {ID: "2"}},
+	{{ID: "3"}, {ID: "4"}},
+	{{ID: "5"}},
+}
+
+func TestQueryIter(t *testing.T) {
+	q := &pagedQuery{t: t, pages: threePages}
+	container := cosmostest.NewContainer(t, q.handle)
+
+	var ids []string
+	for item, err := range QueryIter[testItem](container, "SELECT * FROM c", azcosmos.NewPartitionKeyString("pk"), nil) {
+		require.NoError(t, err)
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, ids)
+	assert.Equal(t, 3, q.fetched)
+}
+
+func TestQueryIter_StopEarly(t *testing.T) {
+	q := &pagedQuery{t: t, pages: threePages}
+	container := cosmostest.NewContainer(t, q.handle)
+
+	for item, err := range QueryIter[testItem](container, "SELECT * FROM c", azcosmos.NewPartitionKeyString("pk"), nil) {
+		require.NoError(t, err)
+		if item.ID == "2" {
+			break
+		}
+	}
+	assert.Equal(t, 1, q.fetched)
+}
+
+func TestQueryIter_Error(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		cosmostest.WriteError(w, http.StatusBadRequest, "BadRequest", "syntax error")
+	})
+
+	var errs int
+	for _, err := range QueryIter[testItem](container, "SELECT", azcosmos.NewPartitionKeyString("pk"), nil) {
+		assert.Error(t, err)
+		errs++
+	}
+	assert.Equal(t, 1, errs)
+}
+
+func TestQueryIterCtx_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, err := range QueryIterCtx[testItem](ctx, cosmostest.NewContainer(t, cosmostest.Unexpected(t)), "SELECT * FROM c", azcosmos.NewPartitionKeyString("pk"), nil) {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func TestQueryPages(t *testing.T) {
+	q := &pagedQuery{t: t, pages: threePages, metrics: "totalExecutionTimeInMs=1.5;retrievedDocumentCount=2"}
+	container := cosmostest.NewContainer(t, q.handle)
+
+	var pages []QueryPage[testItem]
+	for page, err := range QueryPages[testItem](container, "SELECT * FROM c", azcosmos.NewPartitionKeyString("pk"), nil) {
+		require.NoError(t, err)
+		pages = append(pages, page)
+	}
+	require.Len(t, pages, 3)
+	assert.Len(t, pages[0].Items, 2)
+	assert.Equal(t, "1", pages[0].ContinuationToken)
+	assert.Equal(t, "2", pages[1].ContinuationToken)
+	assert.Empty(t, pages[2].ContinuationToken)
+	assert.Equal(t, 2.5, pages[0].RequestCharge)
+	require.NotNil(t, pages[0].Metrics)
+	assert.Equal(t, 1.5, pages[0].Metrics.TotalExecutionTimeInMs)
+}
+
+func TestExecuteQueryPage(t *testing.T) {
+	q := &pagedQuery{t: t, pages: threePages}
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.Header.Get("x-ms-max-item-count"))
+		q.handle(w, r)
+	})
+
+	var ids []string
+	token := ""
+	for {
+		page, err := ExecuteQueryPage[testItem](container, "SELECT * FROM c", azcosmos.NewPartitionKeyString("pk"), 2, token, nil)
+		require.NoError(t, err)
+		for _, item := range page.Items {
+			ids = append(ids, item.ID)
+		}
+		if page.ContinuationToken == "" {
+			break
+		}
+		token = page.ContinuationToken
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, ids)
+	assert.Equal(t, 3, q.fetched)
+}
+
+func TestExecuteQueryPage_Resume(t *testing.T) {
+	q := &pagedQuery{t: t, pages: threePages}
+	container := cosmostest.NewContainer(t, q.handle)
+
+	page, err := ExecuteQueryPage[testItem](container, "SELECT * FROM c", azcosmos.NewPartitionKeyString("pk"), 2, "2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []testItem{{ID: "5"}}, page.Items)
+	assert.Empty(t, page.ContinuationToken)
+	assert.Equal(t, 1, q.fetched)
+}
@@ -4,15 +4,22 @@ package operations
 import (
 	"context"
 	"encoding/json"
+	"errors"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
 	"github.com/abhirockzz/cosmosdb-go-sdk-helper/operations/metrics"
 )
 
 // ==== CREATE OPERATIONS ====
 
 // InsertItemWithResponse inserts an item into the specified container and returns the inserted item.
-func InsertItemWithResponse[T any](container *azcosmos.ContainerClient, item T, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions) (T, error) {
+func InsertItemWithResponse[T any](container *azcosmos.ContainerClient, item T, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return InsertItemWithResponseCtx(context.Background(), container, item, partitionKey, opts, callOpts...)
+}
+
+// InsertItemWithResponseCtx is like InsertItemWithResponse but uses the provided context for the Cosmos DB call.
+func InsertItemWithResponseCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, item T, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
 	if opts == nil {
 		opts = &azcosmos.ItemOptions{}
 	}
@@ -20,36 +27,104 @@ func InsertItemWithResponse[T any](container *azcosmos.ContainerClient, item T,
 
 	itemBytes, err := json.Marshal(item)
 	if err != nil {
-		return item, err
+		return item, operationError("CreateItem", container, "", err)
 	}
 
-	response, err := container.CreateItem(context.Background(), partitionKey, itemBytes, opts)
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.CreateItem(ctx, partitionKey, itemBytes, opts)
+	})
 	if err != nil {
-		return item, err
+		return item, operationError("CreateItem", container, "", err)
 	}
 
 	if err := json.Unmarshal(response.Value, &item); err != nil {
-		return item, err
+		return item, operationError("CreateItem", container, "", err)
 	}
 
 	return item, nil
 }
 
+// UpsertItemWithResponse creates or replaces an item in the specified container and returns the stored item.
+func UpsertItemWithResponse[T any](container *azcosmos.ContainerClient, item T, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return UpsertItemWithResponseCtx(context.Background(), container, item, partitionKey, opts, callOpts...)
+}
+
+// UpsertItemWithResponseCtx is like UpsertItemWithResponse but uses the provided context for the Cosmos DB call.
+func UpsertItemWithResponseCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, item T, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	if opts == nil {
+		opts = &azcosmos.ItemOptions{}
+	}
+	opts.EnableContentResponseOnWrite = true
+
+	itemBytes, err := json.Marshal(item)
+	if err != nil {
+		return item, operationError("UpsertItem", container, "", err)
+	}
+
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.UpsertItem(ctx, partitionKey, itemBytes, opts)
+	})
+	if err != nil {
+		return item, operationError("UpsertItem", container, "", err)
+	}
+
+	if err := json.Unmarshal(response.Value, &item); err != nil {
+		return item, operationError("UpsertItem", container, "", err)
+	}
+
+	return item, nil
+}
+
+// InsertItemAutoPK is like InsertItemWithResponse but derives the partition key from the item's `cosmos:"pk"` tags (see PartitionKeyOf).
+func InsertItemAutoPK[T any](container *azcosmos.ContainerClient, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return InsertItemAutoPKCtx(context.Background(), container, item, opts, callOpts...)
+}
+
+// InsertItemAutoPKCtx is like InsertItemAutoPK but uses the provided context for the Cosmos DB call.
+func InsertItemAutoPKCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	partitionKey, err := PartitionKeyOf(item)
+	if err != nil {
+		return item, operationError("CreateItem", container, "", err)
+	}
+	return InsertItemWithResponseCtx(ctx, container, item, partitionKey, opts, callOpts...)
+}
+
+// UpsertItemAutoPK is like UpsertItemWithResponse but derives the partition key from the item's `cosmos:"pk"` tags (see PartitionKeyOf).
+func UpsertItemAutoPK[T any](container *azcosmos.ContainerClient, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return UpsertItemAutoPKCtx(context.Background(), container, item, opts, callOpts...)
+}
+
+// UpsertItemAutoPKCtx is like UpsertItemAutoPK but uses the provided context for the Cosmos DB call.
+func UpsertItemAutoPKCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	partitionKey, err := PartitionKeyOf(item)
+	if err != nil {
+		return item, operationError("UpsertItem", container, "", err)
+	}
+	return UpsertItemWithResponseCtx(ctx, container, item, partitionKey, opts, callOpts...)
+}
+
 // ==== READ OPERATIONS ====
 
 // GetItem retrieves a single item from a Cosmos DB container
 // Returns the unmarshaled item of type T or an error if the item cannot be retrieved or unmarshaled.
-func GetItem[T any](container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions) (T, error) {
+func GetItem[T any](container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return GetItemCtx[T](context.Background(), container, itemID, partitionKey, opts, callOpts...)
+}
+
+// GetItemCtx is like GetItem but uses the provided context for the Cosmos DB call.
+func GetItemCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
 
 	var typedItem T
 
-	response, err := container.ReadItem(context.Background(), partitionKey, itemID, opts)
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.ReadItem(ctx, partitionKey, itemID, opts)
+	})
 	if err != nil {
-		return typedItem, err
+		return typedItem, operationError("ReadItem", container, itemID, err)
 	}
 
 	if err := json.Unmarshal(response.Value, &typedItem); err != nil {
-		return typedItem, err
+		return typedItem, operationError("ReadItem", container, itemID, err)
 	}
 
 	return typedItem, nil
@@ -63,22 +138,31 @@ type QueryResult[T any] struct {
 
 // ExecuteQuery executes a SQL query against a Cosmos DB container and returns strongly typed results.
 // Returns a slice of unmarshaled items of type T or an error if the query fails.
-func ExecuteQuery[T any](container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions) ([]T, error) {
+func ExecuteQuery[T any](container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) ([]T, error) {
+	return ExecuteQueryCtx[T](context.Background(), container, query, partitionKey, opts, callOpts...)
+}
+
+// ExecuteQueryCtx is like ExecuteQuery but uses the provided context for every page request.
+// Paging stops as soon as the context is cancelled.
+func ExecuteQueryCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) ([]T, error) {
 
 	var items []T
 	queryPager := container.NewQueryItemsPager(query, partitionKey, opts)
 
 	for queryPager.More() {
-		queryResponse, err := queryPager.NextPage(context.Background())
+		if err := ctx.Err(); err != nil {
+			return nil, operationError("QueryItems", container, "", err)
+		}
+		queryResponse, err := call(ctx, callOpts, queryPager.NextPage)
 		if err != nil {
-			return nil, err
+			return nil, operationError("QueryItems", container, "", err)
 		}
 
 		// Process each item in the page
 		for _, item := range queryResponse.Items {
 			var typedItem T
 			if err := json.Unmarshal(item, &typedItem); err != nil {
-				return nil, err
+				return nil, operationError("QueryItems", container, "", err)
 			}
 			items = append(items, typedItem)
 		}
@@ -87,7 +171,15 @@ func ExecuteQuery[T any](container *azcosmos.ContainerClient, query string, part
 	return items, nil
 }
 
-func ExecuteQueryWithMetrics[T any](container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions) (QueryResult[T], error) {
+// ExecuteQueryWithMetrics executes a SQL query like ExecuteQuery and additionally collects per page query metrics
+// along with the total request charge across all pages.
+func ExecuteQueryWithMetrics[T any](container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) (QueryResult[T], error) {
+	return ExecuteQueryWithMetricsCtx[T](context.Background(), container, query, partitionKey, opts, callOpts...)
+}
+
+// ExecuteQueryWithMetricsCtx is like ExecuteQueryWithMetrics but uses the provided context for every page request.
+// Paging stops as soon as the context is cancelled.
+func ExecuteQueryWithMetricsCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, query string, partitionKey azcosmos.PartitionKey, opts *azcosmos.QueryOptions, callOpts ...CallOption) (QueryResult[T], error) {
 	if opts == nil {
 		opts = &azcosmos.QueryOptions{}
 	}
@@ -101,16 +193,19 @@ func ExecuteQueryWithMetrics[T any](container *azcosmos.ContainerClient, query s
 	queryPager := container.NewQueryItemsPager(query, partitionKey, opts)
 
 	for queryPager.More() {
-		queryResponse, err := queryPager.NextPage(context.Background())
+		if err := ctx.Err(); err != nil {
+			return QueryResult[T]{}, operationError("QueryItems", container, "", err)
+		}
+		queryResponse, err := call(ctx, callOpts, queryPager.NextPage)
 		if err != nil {
-			return QueryResult[T]{}, err
+			return QueryResult[T]{}, operationError("QueryItems", container, "", err)
 		}
 
 		// Process each item in the page
 		for _, item := range queryResponse.Items {
 			var typedItem T
 			if err := json.Unmarshal(item, &typedItem); err != nil {
-				return QueryResult[T]{}, err
+				return QueryResult[T]{}, operationError("QueryItems", container, "", err)
 			}
 			items = append(items, typedItem)
 		}
@@ -118,7 +213,7 @@ func ExecuteQueryWithMetrics[T any](container *azcosmos.ContainerClient, query s
 		if queryResponse.QueryMetrics != nil {
 			qm, err := metrics.ParseQueryMetrics(*queryResponse.QueryMetrics)
 			if err != nil {
-				return QueryResult[T]{}, err
+				return QueryResult[T]{}, operationError("QueryItems", container, "", err)
 			}
 			metricsList = append(metricsList, qm)
 		}
@@ -133,7 +228,12 @@ func ExecuteQueryWithMetrics[T any](container *azcosmos.ContainerClient, query s
 // ==== UPDATE OPERATIONS ====
 
 // ReplaceItemWithResponse replaces an item in the specified container and returns the replaced item.
-func ReplaceItemWithResponse[T any](container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, item T, opts *azcosmos.ItemOptions) (T, error) {
+func ReplaceItemWithResponse[T any](container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return ReplaceItemWithResponseCtx(context.Background(), container, itemID, partitionKey, item, opts, callOpts...)
+}
+
+// ReplaceItemWithResponseCtx is like ReplaceItemWithResponse but uses the provided context for the Cosmos DB call.
+func ReplaceItemWithResponseCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
 	if opts == nil {
 		opts = &azcosmos.ItemOptions{}
 	}
@@ -141,17 +241,92 @@ func ReplaceItemWithResponse[T any](container *azcosmos.ContainerClient, itemID
 
 	itemBytes, err := json.Marshal(item)
 	if err != nil {
-		return item, err
+		return item, operationError("ReplaceItem", container, itemID, err)
 	}
 
-	response, err := container.ReplaceItem(context.Background(), partitionKey, itemID, itemBytes, opts)
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.ReplaceItem(ctx, partitionKey, itemID, itemBytes, opts)
+	})
 	if err != nil {
-		return item, err
+		return item, operationError("ReplaceItem", container, itemID, err)
 	}
 
 	if err := json.Unmarshal(response.Value, &item); err != nil {
-		return item, err
+		return item, operationError("ReplaceItem", container, itemID, err)
 	}
 
 	return item, nil
 }
+
+// ReplaceItemAutoPK is like ReplaceItemWithResponse but derives the partition key from the item's `cosmos:"pk"` tags (see PartitionKeyOf).
+func ReplaceItemAutoPK[T any](container *azcosmos.ContainerClient, itemID string, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return ReplaceItemAutoPKCtx(context.Background(), container, itemID, item, opts, callOpts...)
+}
+
+// ReplaceItemAutoPKCtx is like ReplaceItemAutoPK but uses the provided context for the Cosmos DB call.
+func ReplaceItemAutoPKCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, itemID string, item T, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	partitionKey, err := PartitionKeyOf(item)
+	if err != nil {
+		return item, operationError("ReplaceItem", container, itemID, err)
+	}
+	return ReplaceItemWithResponseCtx(ctx, container, itemID, partitionKey, item, opts, callOpts...)
+}
+
+// PatchItemWithResponse applies the patch operations to an item in the specified container and returns the patched item.
+func PatchItemWithResponse[T any](container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, ops azcosmos.PatchOperations, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return PatchItemWithResponseCtx[T](context.Background(), container, itemID, partitionKey, ops, opts, callOpts...)
+}
+
+// PatchItemWithResponseCtx is like PatchItemWithResponse but uses the provided context for the Cosmos DB call.
+func PatchItemWithResponseCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, ops azcosmos.PatchOperations, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	if opts == nil {
+		opts = &azcosmos.ItemOptions{}
+	}
+	opts.EnableContentResponseOnWrite = true
+
+	var typedItem T
+
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.PatchItem(ctx, partitionKey, itemID, ops, opts)
+	})
+	if err != nil {
+		return typedItem, operationError("PatchItem", container, itemID, err)
+	}
+
+	if err := json.Unmarshal(response.Value, &typedItem); err != nil {
+		return typedItem, operationError("PatchItem", container, itemID, err)
+	}
+
+	return typedItem, nil
+}
+
+// ==== DELETE OPERATIONS ====
+
+// DeleteItem deletes an item from the specified container.
+func DeleteItem(container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) error {
+	return DeleteItemCtx(context.Background(), container, itemID, partitionKey, opts, callOpts...)
+}
+
+// DeleteItemCtx is like DeleteItem but uses the provided context for the Cosmos DB call.
+func DeleteItemCtx(ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) error {
+	_, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.DeleteItem(ctx, partitionKey, itemID, opts)
+	})
+	return operationError("DeleteItem", container, itemID, err)
+}
+
+// DeleteItemIfExists deletes an item from the specified container, treating a missing item as success.
+// This is useful for idempotent cleanup where the item may already have been removed.
+func DeleteItemIfExists(container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) error {
+	return DeleteItemIfExistsCtx(context.Background(), container, itemID, partitionKey, opts, callOpts...)
+}
+
+// DeleteItemIfExistsCtx is like DeleteItemIfExists but uses the provided context for the Cosmos DB call.
+func DeleteItemIfExistsCtx(ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) error {
+	err := DeleteItemCtx(ctx, container, itemID, partitionKey, opts, callOpts...)
+	if errors.Is(err, cosmosdb_errors.ErrNotFound) {
+		// Item doesn't exist (or was deleted by another process), treat as success
+		return nil
+	}
+	return err
+}
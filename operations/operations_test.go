@@ -0,0 +1,154 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestExecuteQueryCtx_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	items, err := ExecuteQueryCtx[testItem](ctx, cosmostest.NewContainer(t, cosmostest.Unexpected(t)), "SELECT * FROM c", azcosmos.NewPartitionKey(), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, items)
+}
+
+func TestExecuteQueryWithMetricsCtx_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := ExecuteQueryWithMetricsCtx[testItem](ctx, cosmostest.NewContainer(t, cosmostest.Unexpected(t)), "SELECT * FROM c", azcosmos.NewPartitionKey(), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, result.Items)
+}
+
+func TestUpsertItemWithResponse(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "true", r.Header.Get("x-ms-documentdb-is-upsert"))
+		var item testItem
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&item))
+		item.Count++
+		cosmostest.WriteJSON(w, http.StatusOK, item)
+	})
+
+	item, err := UpsertItemWithResponse(container, testItem{ID: "1", Name: "one"}, azcosmos.NewPartitionKeyString("1"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, testItem{ID: "1", Name: "one", Count: 1}, item)
+}
+
+func TestPatchItemWithResponse(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/dbs/db/colls/container/docs/1", r.URL.Path)
+		cosmostest.WriteJSON(w, http.StatusOK, testItem{ID: "1", Name: "patched"})
+	})
+
+	ops := azcosmos.PatchOperations{}
+	ops.AppendSet("/name", "patched")
+	item, err := PatchItemWithResponse[testItem](container, "1", azcosmos.NewPartitionKeyString("1"), ops, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "patched", item.Name)
+}
+
+func TestDeleteItem_NotFound(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "Entity with the specified id does not exist in the system.")
+	})
+
+	err := DeleteItem(container, "1", azcosmos.NewPartitionKeyString("1"), nil)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrNotFound)
+
+	err = DeleteItemIfExists(container, "1", azcosmos.NewPartitionKeyString("1"), nil)
+	assert.NoError(t, err)
+}
+
+func TestDeleteItemIfExists_ReadSessionNotAvailable(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-substatus", "1002")
+		cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "read session not available")
+	})
+
+	// a lagging replica is not a missing item
+	err := DeleteItemIfExists(container, "1", azcosmos.NewPartitionKeyString("1"), nil)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrServiceUnavailable)
+}
+
+func TestDeleteItemIfExists_OtherError(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		// RBAC: the principal lacks a data action
+		w.Header().Set("x-ms-substatus", "5301")
+		cosmostest.WriteError(w, http.StatusForbidden, "Forbidden", "forbidden")
+	})
+
+	err := DeleteItemIfExists(container, "1", azcosmos.NewPartitionKeyString("1"), nil)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrUnauthorized)
+}
+
+// pagedQuery serves query requests from pages, using the page index as the continuation token.
+type pagedQuery struct {
+	t       *testing.T
+	pages   [][]testItem
+	metrics string
+	fetched int
+}
+
+func (q *pagedQuery) handle(w http.ResponseWriter, r *http.Request) {
+	assert.True(q.t, cosmostest.IsQuery(r))
+	index := 0
+	if token := r.Header.Get("x-ms-continuation"); token != "" {
+		var err error
+		index, err = strconv.Atoi(token)
+		require.NoError(q.t, err)
+	}
+	q.fetched++
+	if index+1 < len(q.pages) {
+		w.Header().Set("x-ms-continuation", strconv.Itoa(index+1))
+	}
+	if q.metrics != "" {
+		w.Header().Set("x-ms-documentdb-query-metrics", q.metrics)
+	}
+	w.Header().Set("x-ms-request-charge", "2.5")
+	cosmostest.WriteDocuments(w, q.pages[index], "")
+}
+
+func TestGetItemCtx_RetryPolicy(t *testing.T) {
+	attempts := 0
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("x-ms-retry-after-ms", "1")
+			cosmostest.WriteError(w, http.StatusServiceUnavailable, "ServiceUnavailable", "unavailable")
+			return
+		}
+		cosmostest.WriteJSON(w, http.StatusOK, testItem{ID: "1", Name: "a"})
+	})
+	pk := azcosmos.NewPartitionKeyString("1")
+
+	_, err := GetItem[testItem](container, "1", pk, nil)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrServiceUnavailable, "without a policy the call is not retried")
+
+	attempts = 0
+	item, err := GetItem[testItem](container, "1", pk, nil, WithRetry(&retry.Policy{MaxAttempts: 3}))
+	require.NoError(t, err)
+	assert.Equal(t, "a", item.Name)
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,288 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// maxPartitionKeyLevels is the maximum number of levels in a hierarchical partition key.
+const maxPartitionKeyLevels = 3
+
+// ErrNoPartitionKeyField is returned when a type has no field tagged with `cosmos:"pk"`.
+var ErrNoPartitionKeyField = errors.New("no field tagged with cosmos:\"pk\"")
+
+// pkField is a struct field that holds (one level of) the partition key.
+type pkField struct {
+	name  string
+	index []int
+	path  string
+	level int
+}
+
+type pkMetadata struct {
+	fields []pkField
+	err    error
+}
+
+// pkCache caches partition key metadata per type, since reflection is done on every write.
+var pkCache sync.Map // map[reflect.Type]*pkMetadata
+
+// PartitionKeyOf derives the partition key of item from the fields tagged with `cosmos:"pk"`.
+//
+// A single partition key is declared with `cosmos:"pk"`. The levels of a hierarchical partition key
+// are declared in order with `cosmos:"pk,1"`, `cosmos:"pk,2"` and `cosmos:"pk,3"`.
+// Tagged fields may be nested in (embedded) structs. String, bool and numeric fields are supported;
+// a nil pointer field results in a null partition key value.
+func PartitionKeyOf[T any](item T) (azcosmos.PartitionKey, error) {
+	v := reflect.ValueOf(&item).Elem()
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return azcosmos.PartitionKey{}, errors.New("cannot derive partition key from nil item")
+		}
+		v = v.Elem()
+	}
+
+	md := partitionKeyMetadata(v.Type())
+	if md.err != nil {
+		return azcosmos.PartitionKey{}, md.err
+	}
+
+	pk := azcosmos.NewPartitionKey()
+	for _, f := range md.fields {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			return azcosmos.PartitionKey{}, fmt.Errorf("partition key field %s is not reachable: %w", f.name, err)
+		}
+		pk, err = appendPartitionKeyValue(pk, fv)
+		if err != nil {
+			return azcosmos.PartitionKey{}, fmt.Errorf("partition key field %s: %w", f.name, err)
+		}
+	}
+	return pk, nil
+}
+
+// PartitionKeyPaths returns the partition key paths (e.g. ["/tenantId", "/userId"]) declared by the `cosmos:"pk"` tags of T, in level order.
+func PartitionKeyPaths[T any]() ([]string, error) {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	md := partitionKeyMetadata(t)
+	if md.err != nil {
+		return nil, md.err
+	}
+	paths := make([]string, 0, len(md.fields))
+	for _, f := range md.fields {
+		paths = append(paths, f.path)
+	}
+	return paths, nil
+}
+
+// PartitionKeyFromDocument derives the partition key of a document decoded into a map (e.g. by a map[string]any query)
+// from the paths of a partition key definition, such as the one returned by reading the container.
+// Nested paths like /address/city are followed through nested maps; a null value results in a null partition key value.
+func PartitionKeyFromDocument(doc map[string]any, def azcosmos.PartitionKeyDefinition) (azcosmos.PartitionKey, error) {
+	if len(def.Paths) == 0 {
+		return azcosmos.PartitionKey{}, errors.New("partition key definition has no paths")
+	}
+	pk := azcosmos.NewPartitionKey()
+	for _, path := range def.Paths {
+		var value any = doc
+		for _, name := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return azcosmos.PartitionKey{}, fmt.Errorf("document has no value at partition key path %s", path)
+			}
+			if value, ok = object[strings.Trim(name, `"`)]; !ok {
+				return azcosmos.PartitionKey{}, fmt.Errorf("document has no value at partition key path %s", path)
+			}
+		}
+		if number, ok := value.(json.Number); ok {
+			f, err := number.Float64()
+			if err != nil {
+				return azcosmos.PartitionKey{}, fmt.Errorf("partition key path %s: %w", path, err)
+			}
+			value = f
+		}
+		if value == nil {
+			pk = pk.AppendNull()
+			continue
+		}
+		var err error
+		if pk, err = appendPartitionKeyValue(pk, reflect.ValueOf(value)); err != nil {
+			return azcosmos.PartitionKey{}, fmt.Errorf("partition key path %s: %w", path, err)
+		}
+	}
+	return pk, nil
+}
+
+// ValidatePartitionKeyDefinition checks that the `cosmos:"pk"` tags of T match the partition key definition of a container.
+func ValidatePartitionKeyDefinition[T any](def azcosmos.PartitionKeyDefinition) error {
+	paths, err := PartitionKeyPaths[T]()
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(paths, def.Paths) {
+		return fmt.Errorf("partition key of %s is %v but container uses %v", reflect.TypeFor[T](), paths, def.Paths)
+	}
+	return nil
+}
+
+// ValidatePartitionKey reads the container properties and checks that the `cosmos:"pk"` tags of T match its partition key definition.
+// Call this at startup to catch mismatches before the first write.
+func ValidatePartitionKey[T any](container *azcosmos.ContainerClient, callOpts ...CallOption) error {
+	return ValidatePartitionKeyCtx[T](context.Background(), container, callOpts...)
+}
+
+// ValidatePartitionKeyCtx is like ValidatePartitionKey but uses the provided context for the Cosmos DB call.
+func ValidatePartitionKeyCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, callOpts ...CallOption) error {
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ContainerResponse, error) {
+		return container.Read(ctx, nil)
+	})
+	if err != nil {
+		return operationError("ReadContainer", container, "", err)
+	}
+	if response.ContainerProperties == nil {
+		return fmt.Errorf("container %s returned no properties", container.ID())
+	}
+	return ValidatePartitionKeyDefinition[T](response.ContainerProperties.PartitionKeyDefinition)
+}
+
+func partitionKeyMetadata(t reflect.Type) *pkMetadata {
+	if md, ok := pkCache.Load(t); ok {
+		return md.(*pkMetadata)
+	}
+	md := buildPartitionKeyMetadata(t)
+	actual, _ := pkCache.LoadOrStore(t, md)
+	return actual.(*pkMetadata)
+}
+
+func buildPartitionKeyMetadata(t reflect.Type) *pkMetadata {
+	if t.Kind() != reflect.Struct {
+		return &pkMetadata{err: fmt.Errorf("cannot derive partition key from %s: %w", t, ErrNoPartitionKeyField)}
+	}
+
+	var fields []pkField
+	if err := collectPartitionKeyFields(t, nil, nil, "", map[reflect.Type]bool{}, &fields); err != nil {
+		return &pkMetadata{err: err}
+	}
+	if len(fields) == 0 {
+		return &pkMetadata{err: fmt.Errorf("%s: %w", t, ErrNoPartitionKeyField)}
+	}
+
+	if len(fields) == 1 && fields[0].level == 0 {
+		fields[0].level = 1
+	}
+	slices.SortFunc(fields, func(a, b pkField) int { return a.level - b.level })
+	if len(fields) > maxPartitionKeyLevels {
+		return &pkMetadata{err: fmt.Errorf("%s declares %d partition key levels, maximum is %d", t, len(fields), maxPartitionKeyLevels)}
+	}
+	for i, f := range fields {
+		if f.level != i+1 {
+			return &pkMetadata{err: fmt.Errorf("%s: hierarchical partition key fields must be tagged with consecutive levels cosmos:\"pk,1\" to cosmos:\"pk,%d\"", t, len(fields))}
+		}
+	}
+	return &pkMetadata{fields: fields}
+}
+
+func collectPartitionKeyFields(t reflect.Type, index []int, path []string, prefix string, visiting map[reflect.Type]bool, fields *[]pkField) error {
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name, tagged, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		fieldIndex := append(slices.Clone(index), i)
+		fieldPath := path
+		// untagged embedded structs are flattened into the parent
+		if !f.Anonymous || tagged {
+			fieldPath = append(slices.Clone(path), name)
+		}
+
+		if tag, ok := f.Tag.Lookup("cosmos"); ok {
+			level, isPK, err := parseCosmosTag(tag)
+			if err != nil {
+				return fmt.Errorf("field %s%s: %w", prefix, f.Name, err)
+			}
+			if isPK {
+				*fields = append(*fields, pkField{
+					name:  prefix + f.Name,
+					index: fieldIndex,
+					path:  "/" + strings.Join(fieldPath, "/"),
+					level: level,
+				})
+				continue
+			}
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			if err := collectPartitionKeyFields(ft, fieldIndex, fieldPath, prefix+f.Name+".", visiting, fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseCosmosTag parses `cosmos:"pk"` and `cosmos:"pk,<level>"`. Level is 0 when not specified.
+func parseCosmosTag(tag string) (level int, isPK bool, err error) {
+	name, levelStr, hasLevel := strings.Cut(tag, ",")
+	if name != "pk" {
+		return 0, false, nil
+	}
+	if !hasLevel {
+		return 0, true, nil
+	}
+	level, err = strconv.Atoi(levelStr)
+	if err != nil || level < 1 || level > maxPartitionKeyLevels {
+		return 0, false, fmt.Errorf("invalid partition key level %q, must be 1 to %d", levelStr, maxPartitionKeyLevels)
+	}
+	return level, true, nil
+}
+
+func appendPartitionKeyValue(pk azcosmos.PartitionKey, v reflect.Value) (azcosmos.PartitionKey, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return pk.AppendNull(), nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return pk.AppendString(v.String()), nil
+	case reflect.Bool:
+		return pk.AppendBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return pk.AppendNumber(float64(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return pk.AppendNumber(float64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return pk.AppendNumber(v.Float()), nil
+	default:
+		return pk, fmt.Errorf("unsupported partition key type %s", v.Type())
+	}
+}
@@ -0,0 +1,140 @@
+package operations
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pkOrder struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customerId" cosmos:"pk"`
+}
+
+type pkTenant struct {
+	TenantID string `json:"tenantId" cosmos:"pk,1"`
+}
+
+type pkSession struct {
+	pkTenant
+	ID        string  `json:"id"`
+	SessionID *string `json:"sessionId" cosmos:"pk,3"`
+	User      struct {
+		ID int `json:"id" cosmos:"pk,2"`
+	} `json:"user"`
+}
+
+type pkNone struct {
+	ID string `json:"id"`
+}
+
+type pkGap struct {
+	A string `json:"a" cosmos:"pk,1"`
+	B string `json:"b" cosmos:"pk,3"`
+}
+
+type pkUnsupported struct {
+	Tags []string `json:"tags" cosmos:"pk"`
+}
+
+func TestPartitionKeyOf(t *testing.T) {
+	pk, err := PartitionKeyOf(pkOrder{ID: "1", CustomerID: "c42"})
+	require.NoError(t, err)
+	assert.Equal(t, azcosmos.NewPartitionKeyString("c42"), pk)
+
+	// pointers to items are supported too
+	pk, err = PartitionKeyOf(&pkOrder{ID: "1", CustomerID: "c42"})
+	require.NoError(t, err)
+	assert.Equal(t, azcosmos.NewPartitionKeyString("c42"), pk)
+}
+
+func TestPartitionKeyOf_Hierarchical(t *testing.T) {
+	session := "s1"
+	item := pkSession{pkTenant: pkTenant{TenantID: "t1"}, SessionID: &session}
+	item.User.ID = 7
+
+	pk, err := PartitionKeyOf(item)
+	require.NoError(t, err)
+	assert.Equal(t, azcosmos.NewPartitionKeyString("t1").AppendNumber(7).AppendString("s1"), pk)
+
+	item.SessionID = nil
+	pk, err = PartitionKeyOf(item)
+	require.NoError(t, err)
+	assert.Equal(t, azcosmos.NewPartitionKeyString("t1").AppendNumber(7).AppendNull(), pk)
+}
+
+func TestPartitionKeyOf_Errors(t *testing.T) {
+	_, err := PartitionKeyOf(pkNone{})
+	assert.ErrorIs(t, err, ErrNoPartitionKeyField)
+
+	_, err = PartitionKeyOf(map[string]any{"id": "1"})
+	assert.ErrorIs(t, err, ErrNoPartitionKeyField)
+
+	_, err = PartitionKeyOf(pkGap{})
+	assert.ErrorContains(t, err, "consecutive levels")
+
+	_, err = PartitionKeyOf(pkUnsupported{})
+	assert.ErrorContains(t, err, "unsupported partition key type")
+
+	_, err = PartitionKeyOf[*pkOrder](nil)
+	assert.Error(t, err)
+}
+
+func TestPartitionKeyPaths(t *testing.T) {
+	paths, err := PartitionKeyPaths[pkOrder]()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/customerId"}, paths)
+
+	paths, err = PartitionKeyPaths[*pkSession]()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/tenantId", "/user/id", "/sessionId"}, paths)
+}
+
+func TestPartitionKeyFromDocument(t *testing.T) {
+	doc := map[string]any{"tenantId": "t1", "user": map[string]any{"id": float64(7)}, "sessionId": nil}
+	pk, err := PartitionKeyFromDocument(doc, azcosmos.PartitionKeyDefinition{Paths: []string{"/tenantId", "/user/id", "/sessionId"}})
+	require.NoError(t, err)
+	assert.Equal(t, azcosmos.NewPartitionKeyString("t1").AppendNumber(7).AppendNull(), pk)
+
+	_, err = PartitionKeyFromDocument(doc, azcosmos.PartitionKeyDefinition{Paths: []string{"/user/name"}})
+	assert.EqualError(t, err, "document has no value at partition key path /user/name")
+	_, err = PartitionKeyFromDocument(doc, azcosmos.PartitionKeyDefinition{Paths: []string{"/user"}})
+	assert.EqualError(t, err, "partition key path /user: unsupported partition key type map[string]interface {}")
+}
+
+func TestValidatePartitionKeyDefinition(t *testing.T) {
+	assert.NoError(t, ValidatePartitionKeyDefinition[pkOrder](azcosmos.PartitionKeyDefinition{Paths: []string{"/customerId"}}))
+	assert.Error(t, ValidatePartitionKeyDefinition[pkOrder](azcosmos.PartitionKeyDefinition{Paths: []string{"/id"}}))
+	assert.Error(t, ValidatePartitionKeyDefinition[pkSession](azcosmos.PartitionKeyDefinition{Paths: []string{"/tenantId", "/sessionId", "/user/id"}}))
+}
+
+func TestValidatePartitionKey(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/dbs/db/colls/container", r.URL.Path)
+		cosmostest.WriteJSON(w, http.StatusOK, map[string]any{
+			"id":           "container",
+			"partitionKey": map[string]any{"paths": []string{"/customerId"}, "kind": "Hash"},
+		})
+	})
+
+	assert.NoError(t, ValidatePartitionKey[pkOrder](container))
+	assert.Error(t, ValidatePartitionKey[pkSession](container))
+}
+
+func TestInsertItemAutoPK(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `["c42"]`, r.Header.Get("x-ms-documentdb-partitionkey"))
+		cosmostest.WriteJSON(w, http.StatusCreated, pkOrder{ID: "1", CustomerID: "c42"})
+	})
+
+	order, err := InsertItemAutoPK(container, pkOrder{ID: "1", CustomerID: "c42"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c42", order.CustomerID)
+
+	_, err = InsertItemAutoPK(container, pkNone{ID: "1"}, nil)
+	assert.ErrorIs(t, err, ErrNoPartitionKeyField)
+}
@@ -0,0 +1,291 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// MaxPatchOperations is the maximum number of operations Cosmos DB accepts in a single patch request.
+const MaxPatchOperations = 10
+
+// ErrNoPatchOperations is returned when a patch is built without any operations.
+var ErrNoPatchOperations = errors.New("patch requires at least one operation")
+
+// ErrPatchMoveUnsupported is returned for patches with a move operation, which azcosmos.PatchOperations cannot express.
+var ErrPatchMoveUnsupported = errors.New("patch move is not supported by the Go SDK")
+
+// PatchBuilder builds partial document updates for items of type T.
+// Fields are addressed by Go struct field name (e.g. "Address.City") and resolved to JSON paths
+// through the json struct tags of T. Array elements are addressed with a numeric index (e.g. "Tags.0")
+// and "-" appends to the end of an array. A field starting with "/" is used as a raw JSON path.
+//
+// Errors are collected while building and returned by Build or Apply.
+//
+// Cosmos DB also supports a move operation, but azcosmos.PatchOperations has no way to send it: Move always fails
+// with ErrPatchMoveUnsupported. To move a field, Set the new field and Remove the old one in the same patch.
+type PatchBuilder[T any] struct {
+	ops       azcosmos.PatchOperations
+	count     int
+	condition string
+	errs      []error
+}
+
+// NewPatchBuilder returns an empty PatchBuilder for items of type T.
+func NewPatchBuilder[T any]() *PatchBuilder[T] {
+	return &PatchBuilder[T]{}
+}
+
+// Set sets the field to value, creating it if it does not exist.
+func (b *PatchBuilder[T]) Set(field string, value any) *PatchBuilder[T] {
+	if path, ok := b.resolve(field); ok {
+		b.ops.AppendSet(path, value)
+		b.count++
+	}
+	return b
+}
+
+// Add adds value to the field. For arrays, value is inserted at the given index.
+func (b *PatchBuilder[T]) Add(field string, value any) *PatchBuilder[T] {
+	if path, ok := b.resolve(field); ok {
+		b.ops.AppendAdd(path, value)
+		b.count++
+	}
+	return b
+}
+
+// Replace replaces the value of an existing field.
+func (b *PatchBuilder[T]) Replace(field string, value any) *PatchBuilder[T] {
+	if path, ok := b.resolve(field); ok {
+		b.ops.AppendReplace(path, value)
+		b.count++
+	}
+	return b
+}
+
+// Remove removes the field from the document.
+func (b *PatchBuilder[T]) Remove(field string) *PatchBuilder[T] {
+	if path, ok := b.resolve(field); ok {
+		b.ops.AppendRemove(path)
+		b.count++
+	}
+	return b
+}
+
+// Move moves the value of field from to field path. It is not supported by azcosmos.PatchOperations, so it records
+// ErrPatchMoveUnsupported, which is returned by Build and Apply. See PatchBuilder.
+func (b *PatchBuilder[T]) Move(from, path string) *PatchBuilder[T] {
+	b.errs = append(b.errs, fmt.Errorf("move %q to %q: %w", from, path, ErrPatchMoveUnsupported))
+	return b
+}
+
+// Increment increments a numeric field by delta. Use a negative delta to decrement.
+func (b *PatchBuilder[T]) Increment(field string, delta int64) *PatchBuilder[T] {
+	path, ok := b.resolve(field)
+	if !ok {
+		return b
+	}
+	if ft, found := fieldType(reflect.TypeFor[T](), field); found && !isNumeric(ft) {
+		b.errs = append(b.errs, fmt.Errorf("cannot increment non-numeric field %q of type %s", field, ft))
+		return b
+	}
+	b.ops.AppendIncrement(path, delta)
+	b.count++
+	return b
+}
+
+// If makes the patch conditional. The patch is only applied if the document matches the predicate,
+// otherwise Cosmos DB returns HTTP 412 (precondition failed).
+// The predicate can be a complete filter ("FROM c WHERE c.status = 'open'") or just the condition ("c.status = 'open'").
+func (b *PatchBuilder[T]) If(predicate string) *PatchBuilder[T] {
+	predicate = strings.TrimSpace(predicate)
+	if !strings.HasPrefix(strings.ToLower(predicate), "from ") {
+		predicate = "FROM c WHERE " + predicate
+	}
+	b.condition = predicate
+	return b
+}
+
+// Build validates the patch and returns the underlying azcosmos.PatchOperations.
+func (b *PatchBuilder[T]) Build() (azcosmos.PatchOperations, error) {
+	if len(b.errs) > 0 {
+		return azcosmos.PatchOperations{}, errors.Join(b.errs...)
+	}
+	if b.count == 0 {
+		return azcosmos.PatchOperations{}, ErrNoPatchOperations
+	}
+	if b.count > MaxPatchOperations {
+		return azcosmos.PatchOperations{}, fmt.Errorf("patch has %d operations, maximum is %d", b.count, MaxPatchOperations)
+	}
+
+	// the SDK writes the condition into the request body verbatim, so it must not need JSON escaping
+	if strings.ContainsAny(b.condition, "\"\\") {
+		return azcosmos.PatchOperations{}, errors.New("patch condition must use single quotes for string literals")
+	}
+	if strings.ContainsFunc(b.condition, func(r rune) bool { return r < 0x20 }) {
+		return azcosmos.PatchOperations{}, errors.New("patch condition must not contain control characters such as newlines or tabs")
+	}
+
+	ops := b.ops
+	if b.condition != "" {
+		ops.SetCondition(b.condition)
+	}
+	return ops, nil
+}
+
+// Apply validates the patch, applies it to the item and returns the patched item.
+func (b *PatchBuilder[T]) Apply(container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	return b.ApplyCtx(context.Background(), container, itemID, partitionKey, opts, callOpts...)
+}
+
+// ApplyCtx is like Apply but uses the provided context for the Cosmos DB call.
+func (b *PatchBuilder[T]) ApplyCtx(ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, error) {
+	ops, err := b.Build()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return PatchItemWithResponseCtx[T](ctx, container, itemID, partitionKey, ops, opts, callOpts...)
+}
+
+func (b *PatchBuilder[T]) resolve(field string) (string, bool) {
+	path, err := JSONPath[T](field)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return "", false
+	}
+	return path, true
+}
+
+// JSONPath resolves a dotted Go field reference on T (e.g. "Address.City") to a JSON path (e.g. "/address/city")
+// using the json struct tags. A field starting with "/" is returned unchanged.
+func JSONPath[T any](field string) (string, error) {
+	if strings.HasPrefix(field, "/") {
+		return field, nil
+	}
+	if field == "" {
+		return "", errors.New("empty field reference")
+	}
+
+	t := reflect.TypeFor[T]()
+	var path strings.Builder
+	for _, segment := range strings.Split(field, ".") {
+		name, next, err := resolveSegment(t, segment)
+		if err != nil {
+			return "", fmt.Errorf("invalid field %q for type %s: %w", field, reflect.TypeFor[T](), err)
+		}
+		path.WriteString("/")
+		path.WriteString(escapeJSONPointer(name))
+		t = next
+	}
+	return path.String(), nil
+}
+
+// resolveSegment maps a single field reference segment on t to its JSON name and the type it refers to.
+// A nil type means the remaining path cannot be checked (e.g. interface values) and is passed through.
+func resolveSegment(t reflect.Type, segment string) (string, reflect.Type, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface {
+		return segment, nil, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		sf, ok := t.FieldByName(segment)
+		if !ok || !sf.IsExported() {
+			return "", nil, fmt.Errorf("no exported field %q in %s", segment, t)
+		}
+		name, err := jsonFieldPath(t, sf)
+		if err != nil {
+			return "", nil, err
+		}
+		return name, sf.Type, nil
+	case reflect.Slice, reflect.Array:
+		if segment != "-" {
+			if _, err := strconv.Atoi(segment); err != nil {
+				return "", nil, fmt.Errorf("array index %q is not a number", segment)
+			}
+		}
+		return segment, t.Elem(), nil
+	case reflect.Map:
+		return segment, t.Elem(), nil
+	default:
+		return "", nil, fmt.Errorf("cannot address %q inside %s", segment, t)
+	}
+}
+
+// jsonFieldPath returns the JSON path (relative to t) of a possibly promoted struct field,
+// taking into account embedded structs that are flattened by encoding/json.
+func jsonFieldPath(t reflect.Type, sf reflect.StructField) (string, error) {
+	var segments []string
+	current := t
+	for i, idx := range sf.Index {
+		for current.Kind() == reflect.Pointer {
+			current = current.Elem()
+		}
+		f := current.Field(idx)
+		name, tagged, skip := jsonName(f)
+		if skip {
+			return "", fmt.Errorf("field %q is excluded from JSON", f.Name)
+		}
+		last := i == len(sf.Index)-1
+		// untagged embedded structs are flattened into the parent
+		if last || !f.Anonymous || tagged {
+			segments = append(segments, name)
+		}
+		current = f.Type
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// jsonName returns the JSON name of a struct field, whether it came from a json tag, and whether it is skipped.
+func jsonName(f reflect.StructField) (name string, tagged bool, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if name == "" {
+		return f.Name, false, false
+	}
+	return name, true, false
+}
+
+func fieldType(t reflect.Type, field string) (reflect.Type, bool) {
+	if strings.HasPrefix(field, "/") {
+		return nil, false
+	}
+	for _, segment := range strings.Split(field, ".") {
+		_, next, err := resolveSegment(t, segment)
+		if err != nil || next == nil {
+			return nil, false
+		}
+		t = next
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t, true
+}
+
+func isNumeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func escapeJSONPointer(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	return strings.ReplaceAll(s, "/", "~1")
+}
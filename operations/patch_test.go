@@ -0,0 +1,150 @@
+package operations
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type patchAddress struct {
+	City   string `json:"city"`
+	Street string `json:"street,omitempty"`
+}
+
+type patchAudit struct {
+	UpdatedBy string `json:"updatedBy"`
+}
+
+type patchDoc struct {
+	patchAudit
+	ID       string            `json:"id"`
+	Status   string            `json:"status"`
+	Views    int               `json:"views"`
+	Tags     []string          `json:"tags"`
+	Address  *patchAddress     `json:"address"`
+	Labels   map[string]string `json:"labels"`
+	Internal string            `json:"-"`
+	NoTag    string
+	Meta     patchAudit `json:"meta"`
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"Status", "/status"},
+		{"Address.City", "/address/city"},
+		{"Tags.0", "/tags/0"},
+		{"Tags.-", "/tags/-"},
+		{"Labels.env", "/labels/env"},
+		{"Labels.a/b", "/labels/a~1b"},
+		{"NoTag", "/NoTag"},
+		{"UpdatedBy", "/updatedBy"},
+		{"Meta.UpdatedBy", "/meta/updatedBy"},
+		{"/_ts", "/_ts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := JSONPath[patchDoc](tt.field)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJSONPath_Invalid(t *testing.T) {
+	for _, field := range []string{"", "Missing", "Internal", "Tags.first", "Status.Length", "Address.Zip"} {
+		_, err := JSONPath[patchDoc](field)
+		assert.Error(t, err, field)
+	}
+}
+
+func TestPatchBuilder_Build(t *testing.T) {
+	ops, err := NewPatchBuilder[patchDoc]().
+		Set("Status", "closed").
+		Increment("Views", 1).
+		Add("Tags.-", "urgent").
+		Replace("Address.City", "Seattle").
+		Remove("Labels.env").
+		If("c.status = 'open'").
+		Build()
+	require.NoError(t, err)
+
+	body, err := json.Marshal(ops)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"condition": "FROM c WHERE c.status = 'open'",
+		"operations": [
+			{"op": "set", "path": "/status", "value": "closed"},
+			{"op": "incr", "path": "/views", "value": 1},
+			{"op": "add", "path": "/tags/-", "value": "urgent"},
+			{"op": "replace", "path": "/address/city", "value": "Seattle"},
+			{"op": "remove", "path": "/labels/env"}
+		]
+	}`, string(body))
+}
+
+func TestPatchBuilder_FullCondition(t *testing.T) {
+	ops, err := NewPatchBuilder[patchDoc]().Set("Status", "closed").If("from c where c.views > 10").Build()
+	require.NoError(t, err)
+
+	body, err := json.Marshal(ops)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, "from c where c.views > 10", decoded["condition"])
+}
+
+func TestPatchBuilder_Errors(t *testing.T) {
+	_, err := NewPatchBuilder[patchDoc]().Build()
+	assert.ErrorIs(t, err, ErrNoPatchOperations)
+
+	_, err = NewPatchBuilder[patchDoc]().Set("Missing", 1).Build()
+	assert.ErrorContains(t, err, "Missing")
+
+	_, err = NewPatchBuilder[patchDoc]().Move("Status", "State").Build()
+	assert.ErrorIs(t, err, ErrPatchMoveUnsupported)
+
+	_, err = NewPatchBuilder[patchDoc]().Increment("Status", 1).Build()
+	assert.ErrorContains(t, err, "non-numeric")
+
+	_, err = NewPatchBuilder[patchDoc]().Set("Status", "closed").If(`c.status = "open"`).Build()
+	assert.ErrorContains(t, err, "single quotes")
+
+	_, err = NewPatchBuilder[patchDoc]().Set("Status", "closed").If("c.status = 'open'\n\tAND c.views > 10").Build()
+	assert.ErrorContains(t, err, "control characters")
+
+	b := NewPatchBuilder[patchDoc]()
+	for range MaxPatchOperations + 1 {
+		b.Increment("Views", 1)
+	}
+	_, err = b.Build()
+	assert.ErrorContains(t, err, "maximum is 10")
+}
+
+func TestPatchBuilder_Apply(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Len(t, body["operations"], 1)
+		cosmostest.WriteJSON(w, http.StatusOK, patchDoc{ID: "1", Status: "closed"})
+	})
+
+	doc, err := NewPatchBuilder[patchDoc]().Set("Status", "closed").Apply(container, "1", azcosmos.NewPartitionKeyString("1"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "closed", doc.Status)
+}
+
+func TestPatchBuilder_ApplyInvalid(t *testing.T) {
+	container := cosmostest.NewContainer(t, cosmostest.Unexpected(t))
+
+	_, err := NewPatchBuilder[patchDoc]().Apply(container, "1", azcosmos.NewPartitionKeyString("1"), nil)
+	assert.ErrorIs(t, err, ErrNoPatchOperations)
+}
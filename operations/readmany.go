@@ -0,0 +1,224 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+const (
+	defaultReadManyConcurrency    = 8
+	defaultReadManyQueryThreshold = 4
+	defaultReadManyMaxQueryIDs    = 100
+)
+
+// ItemIdentity identifies an item by its id and partition key.
+type ItemIdentity struct {
+	ID           string
+	PartitionKey azcosmos.PartitionKey
+}
+
+// ReadManyOptions configures ReadMany.
+type ReadManyOptions struct {
+	// Concurrency is the number of point reads and queries sent in parallel. Defaults to 8.
+	Concurrency int
+	// QueryThreshold is the number of ids in a partition from which they are fetched with an IN query
+	// instead of point reads. Defaults to 4.
+	QueryThreshold int
+	// MaxQueryIDs is the maximum number of ids in a single IN query. Larger groups are split. Defaults to 100.
+	MaxQueryIDs int
+}
+
+// ReadManyResult is the outcome of ReadMany.
+type ReadManyResult[T any] struct {
+	// Items holds the items that were found, in input order.
+	Items []T
+	// Found reports, for each input identity, whether the item was found.
+	Found []bool
+	// Missing holds the identities of the items that were not found, in input order.
+	Missing       []ItemIdentity
+	RequestCharge float64
+}
+
+// ReadMany fetches many items by id and partition key.
+// Ids are grouped by partition key: small groups are fetched with parallel point reads,
+// larger groups with a single "IN" query per partition, which is cheaper than many point reads.
+// Items that do not exist are reported in ReadManyResult.Missing rather than as an error.
+func ReadMany[T any](container *azcosmos.ContainerClient, identities []ItemIdentity, opts *ReadManyOptions, callOpts ...CallOption) (ReadManyResult[T], error) {
+	return ReadManyCtx[T](context.Background(), container, identities, opts, callOpts...)
+}
+
+// ReadManyCtx is like ReadMany but uses the provided context for all Cosmos DB calls.
+func ReadManyCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, identities []ItemIdentity, opts *ReadManyOptions, callOpts ...CallOption) (ReadManyResult[T], error) {
+	o := ReadManyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultReadManyConcurrency
+	}
+	if o.QueryThreshold <= 0 {
+		o.QueryThreshold = defaultReadManyQueryThreshold
+	}
+	if o.MaxQueryIDs <= 0 {
+		o.MaxQueryIDs = defaultReadManyMaxQueryIDs
+	}
+
+	// group the input positions by partition key and id, so that duplicates are only read once
+	type group struct {
+		partitionKey azcosmos.PartitionKey
+		ids          []string
+		positions    map[string][]int
+	}
+	var groups []*group
+	byPartition := map[string]*group{}
+	for i, identity := range identities {
+		key := fmt.Sprintf("%#v", identity.PartitionKey)
+		g, ok := byPartition[key]
+		if !ok {
+			g = &group{partitionKey: identity.PartitionKey, positions: map[string][]int{}}
+			byPartition[key] = g
+			groups = append(groups, g)
+		}
+		if _, seen := g.positions[identity.ID]; !seen {
+			g.ids = append(g.ids, identity.ID)
+		}
+		g.positions[identity.ID] = append(g.positions[identity.ID], i)
+	}
+
+	var tasks []func(context.Context, *readManyCollector)
+	for _, g := range groups {
+		if len(g.ids) < o.QueryThreshold {
+			for _, id := range g.ids {
+				tasks = append(tasks, func(ctx context.Context, c *readManyCollector) {
+					c.pointRead(ctx, container, id, g.partitionKey, g.positions[id], callOpts)
+				})
+			}
+			continue
+		}
+		for start := 0; start < len(g.ids); start += o.MaxQueryIDs {
+			ids := g.ids[start:min(start+o.MaxQueryIDs, len(g.ids))]
+			tasks = append(tasks, func(ctx context.Context, c *readManyCollector) {
+				c.query(ctx, container, ids, g.partitionKey, g.positions, callOpts)
+			})
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	collector := &readManyCollector{docs: make([]json.RawMessage, len(identities)), cancel: cancel}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, o.Concurrency)
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			task(ctx, collector)
+		}()
+	}
+	wg.Wait()
+
+	result := ReadManyResult[T]{Found: make([]bool, len(identities)), RequestCharge: collector.requestCharge}
+	if len(collector.errs) > 0 {
+		return result, errors.Join(collector.errs...)
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	for i, doc := range collector.docs {
+		if doc == nil {
+			result.Missing = append(result.Missing, identities[i])
+			continue
+		}
+		var item T
+		if err := json.Unmarshal(doc, &item); err != nil {
+			return result, err
+		}
+		result.Items = append(result.Items, item)
+		result.Found[i] = true
+	}
+	return result, nil
+}
+
+// readManyCollector gathers the raw documents of a ReadMany call, indexed by input position.
+type readManyCollector struct {
+	mu            sync.Mutex
+	docs          []json.RawMessage
+	requestCharge float64
+	errs          []error
+	cancel        context.CancelFunc
+}
+
+func (c *readManyCollector) pointRead(ctx context.Context, container *azcosmos.ContainerClient, id string, partitionKey azcosmos.PartitionKey, positions []int, callOpts []CallOption) {
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.ReadItem(ctx, partitionKey, id, nil)
+	})
+	if err != nil {
+		err = operationError("ReadItem", container, id, err)
+		if errors.Is(err, cosmosdb_errors.ErrNotFound) {
+			c.add(nil, nil, cosmosdb_errors.GetError(err).RequestCharge, nil)
+			return
+		}
+		c.add(nil, nil, 0, err)
+		return
+	}
+	c.add(positions, response.Value, float64(response.RequestCharge), nil)
+}
+
+func (c *readManyCollector) query(ctx context.Context, container *azcosmos.ContainerClient, ids []string, partitionKey azcosmos.PartitionKey, positions map[string][]int, callOpts []CallOption) {
+	placeholders := make([]string, len(ids))
+	params := make([]azcosmos.QueryParameter, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("@id%d", i)
+		params[i] = azcosmos.QueryParameter{Name: placeholders[i], Value: id}
+	}
+	query := "SELECT * FROM c WHERE c.id IN (" + strings.Join(placeholders, ", ") + ")"
+
+	pager := container.NewQueryItemsPager(query, partitionKey, &azcosmos.QueryOptions{QueryParameters: params})
+	for pager.More() {
+		if err := ctx.Err(); err != nil {
+			return
+		}
+		response, err := call(ctx, callOpts, pager.NextPage)
+		if err != nil {
+			c.add(nil, nil, 0, operationError("QueryItems", container, "", err))
+			return
+		}
+		c.add(nil, nil, float64(response.RequestCharge), nil)
+		for _, doc := range response.Items {
+			var meta struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(doc, &meta); err != nil {
+				c.add(nil, nil, 0, err)
+				return
+			}
+			c.add(positions[meta.ID], doc, 0, nil)
+		}
+	}
+}
+
+func (c *readManyCollector) add(positions []int, doc json.RawMessage, charge float64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, i := range positions {
+		c.docs[i] = doc
+	}
+	c.requestCharge += charge
+	if err != nil {
+		c.errs = append(c.errs, err)
+		c.cancel()
+	}
+}
@@ -0,0 +1,113 @@
+package operations
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readManyStore serves point reads and IN queries from documents keyed by partition key header and id.
+type readManyStore struct {
+	t          *testing.T
+	docs       map[string]map[string]testItem
+	mu         sync.Mutex
+	pointReads int
+	queries    []map[string]any
+}
+
+func (s *readManyStore) handle(w http.ResponseWriter, r *http.Request) {
+	partition := s.docs[r.Header.Get("x-ms-documentdb-partitionkey")]
+	w.Header().Set("x-ms-request-charge", "1")
+
+	if cosmostest.IsQuery(r) {
+		var body struct {
+			Query      string `json:"query"`
+			Parameters []struct {
+				Value string `json:"value"`
+			} `json:"parameters"`
+		}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.mu.Lock()
+		s.queries = append(s.queries, map[string]any{"query": body.Query, "ids": len(body.Parameters)})
+		s.mu.Unlock()
+
+		var docs []testItem
+		for _, p := range body.Parameters {
+			if doc, ok := partition[p.Value]; ok {
+				docs = append(docs, doc)
+			}
+		}
+		cosmostest.WriteDocuments(w, docs, "")
+		return
+	}
+
+	s.mu.Lock()
+	s.pointReads++
+	s.mu.Unlock()
+	doc, ok := partition[path.Base(r.URL.Path)]
+	if !ok {
+		cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "not found")
+		return
+	}
+	cosmostest.WriteJSON(w, http.StatusOK, doc)
+}
+
+func TestReadMany(t *testing.T) {
+	store := &readManyStore{t: t, docs: map[string]map[string]testItem{
+		`["a"]`: {"1": {ID: "1", Name: "a1"}, "2": {ID: "2", Name: "a2"}, "3": {ID: "3", Name: "a3"}, "4": {ID: "4", Name: "a4"}},
+		`["b"]`: {"1": {ID: "1", Name: "b1"}},
+	}}
+	container := cosmostest.NewContainer(t, store.handle)
+	a, b := azcosmos.NewPartitionKeyString("a"), azcosmos.NewPartitionKeyString("b")
+
+	identities := []ItemIdentity{
+		{ID: "4", PartitionKey: a},
+		{ID: "1", PartitionKey: b},
+		{ID: "1", PartitionKey: a},
+		{ID: "9", PartitionKey: a},
+		{ID: "2", PartitionKey: b},
+		{ID: "3", PartitionKey: a},
+		{ID: "4", PartitionKey: a},
+	}
+	result, err := ReadMany[testItem](container, identities, &ReadManyOptions{QueryThreshold: 3, MaxQueryIDs: 3})
+	require.NoError(t, err)
+
+	names := make([]string, len(result.Items))
+	for i, item := range result.Items {
+		names[i] = item.Name
+	}
+	assert.Equal(t, []string{"a4", "b1", "a1", "a3", "a4"}, names)
+	assert.Equal(t, []bool{true, true, true, false, false, true, true}, result.Found)
+	assert.Equal(t, []ItemIdentity{{ID: "9", PartitionKey: a}, {ID: "2", PartitionKey: b}}, result.Missing)
+
+	// partition "a" has four distinct ids, split into two queries; partition "b" uses point reads
+	assert.Equal(t, 2, store.pointReads)
+	assert.ElementsMatch(t, []map[string]any{
+		{"query": "SELECT * FROM c WHERE c.id IN (@id0, @id1, @id2)", "ids": 3},
+		{"query": "SELECT * FROM c WHERE c.id IN (@id0)", "ids": 1},
+	}, store.queries)
+	assert.Equal(t, 4.0, result.RequestCharge)
+}
+
+func TestReadMany_Error(t *testing.T) {
+	container := cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		// RBAC: the principal lacks a data action
+		w.Header().Set("x-ms-substatus", "5301")
+		cosmostest.WriteError(w, http.StatusForbidden, "Forbidden", "denied")
+	})
+
+	_, err := ReadMany[testItem](container, []ItemIdentity{{ID: "1", PartitionKey: azcosmos.NewPartitionKeyString("a")}}, nil)
+	var opErr *cosmosdb_errors.OperationError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "ReadItem", opErr.Operation)
+	assert.Equal(t, "1", opErr.ItemID)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrUnauthorized)
+}
@@ -0,0 +1,37 @@
+package operations
+
+import (
+	"context"
+
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/retry"
+)
+
+// CallOption configures how a helper calls Cosmos DB. Every helper that calls Cosmos DB accepts call options
+// as its last arguments.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	retry  bool
+	policy *retry.Policy
+}
+
+// WithRetry makes a helper retry Cosmos DB calls that fail with transient or throttling errors, using policy
+// (see retry.Do). A nil policy uses the defaults of the retry package. Without WithRetry, every call is made once.
+func WithRetry(policy *retry.Policy) CallOption {
+	return func(o *callOptions) {
+		o.retry = true
+		o.policy = policy
+	}
+}
+
+// call invokes fn, retrying transient and throttling errors if the call options include WithRetry.
+func call[R any](ctx context.Context, callOpts []CallOption, fn func(context.Context) (R, error)) (R, error) {
+	var o callOptions
+	for _, opt := range callOpts {
+		opt(&o)
+	}
+	if !o.retry {
+		return fn(ctx)
+	}
+	return retry.DoValue(ctx, o.policy, fn)
+}
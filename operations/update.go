@@ -0,0 +1,116 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+const (
+	defaultUpdateMaxRetries = 5
+	defaultUpdateBackoff    = 50 * time.Millisecond
+	defaultUpdateMaxBackoff = 2 * time.Second
+)
+
+// UpdateOptions configures UpdateWithRetry.
+type UpdateOptions struct {
+	// MaxRetries is the number of times the read-modify-write cycle is retried after a conflicting write (HTTP 412).
+	// Defaults to 5. Use a negative value to disable retries.
+	MaxRetries int
+	// Backoff is the initial delay before retrying. It doubles on every retry (with jitter). Defaults to 50ms.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. Defaults to 2s.
+	MaxBackoff time.Duration
+	// ItemOptions are passed to the read and replace calls. IfMatchEtag is always set by UpdateWithRetry.
+	ItemOptions *azcosmos.ItemOptions
+}
+
+// UpdateWithRetry performs an optimistic-concurrency read-modify-write of a single item.
+// It reads the item, applies mutate to it, and replaces it only if the item's _etag is unchanged.
+// If another writer modified the item in the meantime (HTTP 412), the cycle is repeated with a fresh read,
+// up to the configured number of retries. If mutate returns an error, the update is aborted and the error is returned.
+func UpdateWithRetry[T any](container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, mutate func(*T) error, opts *UpdateOptions, callOpts ...CallOption) (T, error) {
+	return UpdateWithRetryCtx(context.Background(), container, itemID, partitionKey, mutate, opts, callOpts...)
+}
+
+// UpdateWithRetryCtx is like UpdateWithRetry but uses the provided context for all Cosmos DB calls and while waiting between retries.
+func UpdateWithRetryCtx[T any](ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, mutate func(*T) error, opts *UpdateOptions, callOpts ...CallOption) (T, error) {
+	o := UpdateOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = defaultUpdateMaxRetries
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = defaultUpdateBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaultUpdateMaxBackoff
+	}
+
+	var zero T
+	backoff := o.Backoff
+
+	for attempt := 0; ; attempt++ {
+		item, etag, err := getItemWithETag[T](ctx, container, itemID, partitionKey, o.ItemOptions, callOpts...)
+		if err != nil {
+			return zero, err
+		}
+
+		if err := mutate(&item); err != nil {
+			return zero, err
+		}
+
+		itemOpts := azcosmos.ItemOptions{}
+		if o.ItemOptions != nil {
+			itemOpts = *o.ItemOptions
+		}
+		itemOpts.IfMatchEtag = &etag
+
+		updated, err := ReplaceItemWithResponseCtx(ctx, container, itemID, partitionKey, item, &itemOpts, callOpts...)
+		if err == nil {
+			return updated, nil
+		}
+		if !errors.Is(err, cosmosdb_errors.ErrPreconditionFailed) {
+			return zero, err
+		}
+		if attempt >= o.MaxRetries {
+			return zero, fmt.Errorf("item %s was modified concurrently, giving up after %d attempts: %w", itemID, attempt+1, err)
+		}
+
+		// full jitter keeps concurrent writers from retrying in lockstep
+		delay := time.Duration(rand.Int64N(int64(backoff))) + 1
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, o.MaxBackoff)
+	}
+}
+
+// getItemWithETag reads an item like GetItem and also returns its current ETag.
+func getItemWithETag[T any](ctx context.Context, container *azcosmos.ContainerClient, itemID string, partitionKey azcosmos.PartitionKey, opts *azcosmos.ItemOptions, callOpts ...CallOption) (T, azcore.ETag, error) {
+	var typedItem T
+
+	response, err := call(ctx, callOpts, func(ctx context.Context) (azcosmos.ItemResponse, error) {
+		return container.ReadItem(ctx, partitionKey, itemID, opts)
+	})
+	if err != nil {
+		return typedItem, "", operationError("ReadItem", container, itemID, err)
+	}
+
+	if err := json.Unmarshal(response.Value, &typedItem); err != nil {
+		return typedItem, "", operationError("ReadItem", container, itemID, err)
+	}
+
+	return typedItem, response.ETag, nil
+}
@@ -0,0 +1,99 @@
+package operations
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionedStore serves a single item and rejects replaces whose If-Match does not match the current version.
+// conflicts is the number of replaces that are rejected as if another writer got there first.
+type versionedStore struct {
+	t         *testing.T
+	item      testItem
+	version   int
+	conflicts int32
+	replaces  atomic.Int32
+}
+
+func (s *versionedStore) handle(w http.ResponseWriter, r *http.Request) {
+	etag := `"` + strconv.Itoa(s.version) + `"`
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("etag", etag)
+		cosmostest.WriteJSON(w, http.StatusOK, s.item)
+	case http.MethodPut:
+		n := s.replaces.Add(1)
+		if n <= s.conflicts {
+			// simulate a concurrent writer
+			s.version++
+			s.item.Count += 100
+		}
+		if r.Header.Get("If-Match") != `"`+strconv.Itoa(s.version)+`"` {
+			cosmostest.WriteError(w, http.StatusPreconditionFailed, "PreconditionFailed", "etag mismatch")
+			return
+		}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&s.item))
+		s.version++
+		w.Header().Set("etag", `"`+strconv.Itoa(s.version)+`"`)
+		cosmostest.WriteJSON(w, http.StatusOK, s.item)
+	default:
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func increment(item *testItem) error {
+	item.Count++
+	return nil
+}
+
+func TestUpdateWithRetry(t *testing.T) {
+	store := &versionedStore{t: t, item: testItem{ID: "1", Count: 1}}
+	container := cosmostest.NewContainer(t, store.handle)
+
+	item, err := UpdateWithRetry(container, "1", azcosmos.NewPartitionKeyString("1"), increment, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, item.Count)
+	assert.EqualValues(t, 1, store.replaces.Load())
+}
+
+func TestUpdateWithRetry_RetriesOnConflict(t *testing.T) {
+	store := &versionedStore{t: t, item: testItem{ID: "1", Count: 1}, conflicts: 2}
+	container := cosmostest.NewContainer(t, store.handle)
+
+	item, err := UpdateWithRetry(container, "1", azcosmos.NewPartitionKeyString("1"), increment, &UpdateOptions{Backoff: time.Millisecond})
+	require.NoError(t, err)
+	// both concurrent writes are preserved
+	assert.Equal(t, 202, item.Count)
+	assert.EqualValues(t, 3, store.replaces.Load())
+}
+
+func TestUpdateWithRetry_GivesUp(t *testing.T) {
+	store := &versionedStore{t: t, item: testItem{ID: "1"}, conflicts: 10}
+	container := cosmostest.NewContainer(t, store.handle)
+
+	_, err := UpdateWithRetry(container, "1", azcosmos.NewPartitionKeyString("1"), increment, &UpdateOptions{MaxRetries: 2, Backoff: time.Millisecond})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, cosmosdb_errors.GetError(err).Status)
+	assert.EqualValues(t, 3, store.replaces.Load())
+}
+
+func TestUpdateWithRetry_MutateError(t *testing.T) {
+	store := &versionedStore{t: t, item: testItem{ID: "1"}}
+	container := cosmostest.NewContainer(t, store.handle)
+
+	errAbort := errors.New("abort")
+	_, err := UpdateWithRetry(container, "1", azcosmos.NewPartitionKeyString("1"), func(*testItem) error { return errAbort }, nil)
+	assert.ErrorIs(t, err, errAbort)
+	assert.EqualValues(t, 0, store.replaces.Load())
+}
@@ -0,0 +1,229 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// Condition is a filter expression used in a WHERE clause.
+type Condition interface {
+	// reserve registers the parameter names the condition uses explicitly.
+	reserve(r *renderer) error
+	// render returns the SQL text of the condition, binding values as parameters.
+	render(r *renderer) (string, error)
+}
+
+// Param is a named query parameter used with Raw.
+type Param struct {
+	Name  string
+	Value any
+}
+
+type comparison struct {
+	field    string
+	operator string
+	value    any
+}
+
+func (c comparison) reserve(*renderer) error { return nil }
+
+func (c comparison) render(r *renderer) (string, error) {
+	ref, err := r.field(c.field)
+	if err != nil {
+		return "", err
+	}
+	return ref + " " + c.operator + " " + r.bind(c.value), nil
+}
+
+// Eq matches documents where field equals value.
+func Eq(field string, value any) Condition { return comparison{field, "=", value} }
+
+// Ne matches documents where field does not equal value.
+func Ne(field string, value any) Condition { return comparison{field, "!=", value} }
+
+// Gt matches documents where field is greater than value.
+func Gt(field string, value any) Condition { return comparison{field, ">", value} }
+
+// Gte matches documents where field is greater than or equal to value.
+func Gte(field string, value any) Condition { return comparison{field, ">=", value} }
+
+// Lt matches documents where field is less than value.
+func Lt(field string, value any) Condition { return comparison{field, "<", value} }
+
+// Lte matches documents where field is less than or equal to value.
+func Lte(field string, value any) Condition { return comparison{field, "<=", value} }
+
+type function struct {
+	name  string
+	field string
+	args  []any
+}
+
+func (f function) reserve(*renderer) error { return nil }
+
+func (f function) render(r *renderer) (string, error) {
+	ref, err := r.field(f.field)
+	if err != nil {
+		return "", err
+	}
+	args := []string{ref}
+	for _, a := range f.args {
+		args = append(args, r.bind(a))
+	}
+	return f.name + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// Contains matches documents where the string field contains substr.
+func Contains(field, substr string) Condition { return function{"CONTAINS", field, []any{substr}} }
+
+// StartsWith matches documents where the string field starts with prefix.
+func StartsWith(field, prefix string) Condition { return function{"STARTSWITH", field, []any{prefix}} }
+
+// EndsWith matches documents where the string field ends with suffix.
+func EndsWith(field, suffix string) Condition { return function{"ENDSWITH", field, []any{suffix}} }
+
+// IsDefined matches documents where field is present.
+func IsDefined(field string) Condition { return function{"IS_DEFINED", field, nil} }
+
+// IsNull matches documents where field is null.
+func IsNull(field string) Condition { return function{"IS_NULL", field, nil} }
+
+type arrayContains struct {
+	field string
+	value any
+}
+
+func (a arrayContains) reserve(*renderer) error { return nil }
+
+func (a arrayContains) render(r *renderer) (string, error) {
+	ref, err := r.field(a.field)
+	if err != nil {
+		return "", err
+	}
+	return "ARRAY_CONTAINS(" + ref + ", " + r.bind(a.value) + ")", nil
+}
+
+// ArrayContains matches documents where the array field contains value.
+func ArrayContains(field string, value any) Condition { return arrayContains{field, value} }
+
+type in struct {
+	field  string
+	values []any
+}
+
+func (i in) reserve(*renderer) error { return nil }
+
+func (i in) render(r *renderer) (string, error) {
+	if len(i.values) == 0 {
+		return "", fmt.Errorf("IN on %q requires at least one value", i.field)
+	}
+	ref, err := r.field(i.field)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(i.values))
+	for _, v := range i.values {
+		names = append(names, r.bind(v))
+	}
+	return ref + " IN (" + strings.Join(names, ", ") + ")", nil
+}
+
+// In matches documents where field equals one of values.
+func In(field string, values ...any) Condition { return in{field, values} }
+
+type logical struct {
+	operator   string
+	conditions []Condition
+}
+
+func (l logical) reserve(r *renderer) error {
+	for _, c := range l.conditions {
+		if err := c.reserve(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l logical) render(r *renderer) (string, error) {
+	if len(l.conditions) == 0 {
+		return "", fmt.Errorf("%s requires at least one condition", l.operator)
+	}
+	if len(l.conditions) == 1 {
+		return l.conditions[0].render(r)
+	}
+	parts := make([]string, 0, len(l.conditions))
+	for _, c := range l.conditions {
+		s, err := c.render(r)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "("+s+")")
+	}
+	return strings.Join(parts, " "+l.operator+" "), nil
+}
+
+// And matches documents that satisfy all conditions.
+func And(conditions ...Condition) Condition { return logical{"AND", conditions} }
+
+// Or matches documents that satisfy at least one of the conditions.
+func Or(conditions ...Condition) Condition { return logical{"OR", conditions} }
+
+type not struct {
+	condition Condition
+}
+
+func (n not) reserve(r *renderer) error { return n.condition.reserve(r) }
+
+func (n not) render(r *renderer) (string, error) {
+	s, err := n.condition.render(r)
+	if err != nil {
+		return "", err
+	}
+	return "NOT (" + s + ")", nil
+}
+
+// Not negates a condition.
+func Not(condition Condition) Condition { return not{condition} }
+
+type raw struct {
+	sql    string
+	params []Param
+}
+
+var placeholderPattern = regexp.MustCompile(`@[A-Za-z_][A-Za-z0-9_]*`)
+
+func (rc raw) reserve(r *renderer) error {
+	for _, p := range rc.params {
+		if err := r.reserveName(p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rc raw) render(r *renderer) (string, error) {
+	if strings.TrimSpace(rc.sql) == "" {
+		return "", errors.New("empty raw condition")
+	}
+	declared := map[string]bool{}
+	for _, p := range rc.params {
+		declared[p.Name] = true
+		r.params = append(r.params, azcosmos.QueryParameter{Name: p.Name, Value: p.Value})
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(rc.sql, -1) {
+		if !declared[placeholder] {
+			return "", fmt.Errorf("parameter %s in %q has no value", placeholder, rc.sql)
+		}
+	}
+	return rc.sql, nil
+}
+
+// Raw is an escape hatch for conditions the builder does not cover. Any @name placeholder in sql
+// must have a matching Param; values are passed as query parameters, never concatenated into the text.
+// Fields in sql must be written as SQL references on the "c" alias (e.g. c.address.city).
+func Raw(sql string, params ...Param) Condition { return raw{sql, params} }
@@ -0,0 +1,260 @@
+// Package query provides a builder for parameterised Azure Cosmos DB SQL queries.
+// Values are never concatenated into the query text; they are passed as named @param placeholders
+// that map to azcosmos.QueryOptions.QueryParameters.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/operations"
+)
+
+// Direction is the sort direction used in ORDER BY.
+type Direction string
+
+const (
+	Asc  Direction = "ASC"
+	Desc Direction = "DESC"
+)
+
+// alias is the container alias used in the FROM clause.
+const alias = "c"
+
+var (
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	paramNamePattern  = regexp.MustCompile(`^@[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// reservedKeywords cannot be used in dotted property references (c.value), only as c["value"].
+	// Keywords are case-insensitive.
+	reservedKeywords = map[string]bool{
+		"and": true, "array": true, "as": true, "asc": true, "between": true, "by": true, "case": true,
+		"cast": true, "convert": true, "cross": true, "desc": true, "distinct": true, "else": true, "end": true,
+		"escape": true, "exists": true, "false": true, "for": true, "from": true, "group": true, "having": true,
+		"in": true, "inner": true, "insert": true, "into": true, "is": true, "join": true, "left": true,
+		"like": true, "limit": true, "not": true, "null": true, "offset": true, "on": true, "or": true,
+		"order": true, "outer": true, "over": true, "right": true, "select": true, "set": true, "then": true,
+		"top": true, "true": true, "udf": true, "undefined": true, "update": true, "value": true, "when": true,
+		"where": true, "with": true,
+	}
+)
+
+// Query is a rendered query: the SQL text and its parameters.
+type Query struct {
+	Text       string
+	Parameters []azcosmos.QueryParameter
+}
+
+// Options returns a copy of opts (which may be nil) with the query parameters appended,
+// ready to be passed to operations.ExecuteQuery and friends.
+func (q Query) Options(opts *azcosmos.QueryOptions) *azcosmos.QueryOptions {
+	o := azcosmos.QueryOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.QueryParameters = append(append([]azcosmos.QueryParameter{}, o.QueryParameters...), q.Parameters...)
+	return &o
+}
+
+type orderBy struct {
+	field     string
+	direction Direction
+}
+
+// Builder composes a SELECT query against documents of type T.
+// Fields are referenced by Go struct field name (e.g. "Address.City") and resolved through the json tags of T.
+// For map types (e.g. map[string]any) fields are used as JSON property names. A field starting with "/"
+// is used as a raw JSON path, which is handy for system properties such as "/_ts".
+type Builder[T any] struct {
+	fields     []string
+	count      bool
+	top        *int
+	conditions []Condition
+	orderBy    []orderBy
+	offset     *int
+	limit      *int
+}
+
+// New returns a Builder that selects all documents of type T.
+func New[T any]() *Builder[T] {
+	return &Builder[T]{}
+}
+
+// Select restricts the projection to the given fields. Without Select the query returns whole documents (SELECT *).
+func (b *Builder[T]) Select(fields ...string) *Builder[T] {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Count makes the query return the number of matching documents (SELECT VALUE COUNT(1)).
+func (b *Builder[T]) Count() *Builder[T] {
+	b.count = true
+	return b
+}
+
+// Top limits the number of returned documents. It cannot be combined with Offset.
+func (b *Builder[T]) Top(n int) *Builder[T] {
+	b.top = &n
+	return b
+}
+
+// Where adds filter conditions. Conditions from all Where calls are combined with AND.
+func (b *Builder[T]) Where(conditions ...Condition) *Builder[T] {
+	b.conditions = append(b.conditions, conditions...)
+	return b
+}
+
+// OrderBy adds a sort field. Multiple calls produce a multi-field ORDER BY, which requires a composite index.
+func (b *Builder[T]) OrderBy(field string, direction Direction) *Builder[T] {
+	b.orderBy = append(b.orderBy, orderBy{field: field, direction: direction})
+	return b
+}
+
+// Offset skips offset documents and returns at most limit documents (OFFSET LIMIT). It cannot be combined with Top.
+func (b *Builder[T]) Offset(offset, limit int) *Builder[T] {
+	b.offset = &offset
+	b.limit = &limit
+	return b
+}
+
+// Build validates the query and renders its SQL text and parameters.
+func (b *Builder[T]) Build() (Query, error) {
+	r := &renderer{field: Field[T], used: map[string]bool{}}
+
+	if b.top != nil && b.offset != nil {
+		return Query{}, errors.New("TOP cannot be combined with OFFSET LIMIT")
+	}
+	if b.count && len(b.fields) > 0 {
+		return Query{}, errors.New("COUNT cannot be combined with a field projection")
+	}
+
+	// named parameters of raw conditions are reserved first so that generated names never clash with them
+	for _, c := range b.conditions {
+		if err := c.reserve(r); err != nil {
+			return Query{}, err
+		}
+	}
+
+	var sql strings.Builder
+	sql.WriteString("SELECT ")
+	if b.top != nil {
+		if *b.top < 0 {
+			return Query{}, fmt.Errorf("invalid TOP %d", *b.top)
+		}
+		sql.WriteString("TOP " + strconv.Itoa(*b.top) + " ")
+	}
+
+	switch {
+	case b.count:
+		sql.WriteString("VALUE COUNT(1)")
+	case len(b.fields) == 0:
+		sql.WriteString("*")
+	default:
+		refs := make([]string, 0, len(b.fields))
+		for _, f := range b.fields {
+			ref, err := r.field(f)
+			if err != nil {
+				return Query{}, err
+			}
+			refs = append(refs, ref)
+		}
+		sql.WriteString(strings.Join(refs, ", "))
+	}
+	sql.WriteString(" FROM " + alias)
+
+	if len(b.conditions) > 0 {
+		where, err := And(b.conditions...).render(r)
+		if err != nil {
+			return Query{}, err
+		}
+		sql.WriteString(" WHERE " + where)
+	}
+
+	if len(b.orderBy) > 0 {
+		parts := make([]string, 0, len(b.orderBy))
+		for _, o := range b.orderBy {
+			if o.direction != Asc && o.direction != Desc {
+				return Query{}, fmt.Errorf("invalid sort direction %q", o.direction)
+			}
+			ref, err := r.field(o.field)
+			if err != nil {
+				return Query{}, err
+			}
+			parts = append(parts, ref+" "+string(o.direction))
+		}
+		sql.WriteString(" ORDER BY " + strings.Join(parts, ", "))
+	}
+
+	if b.offset != nil {
+		if *b.offset < 0 || *b.limit < 0 {
+			return Query{}, fmt.Errorf("invalid OFFSET %d LIMIT %d", *b.offset, *b.limit)
+		}
+		sql.WriteString(" OFFSET " + strconv.Itoa(*b.offset) + " LIMIT " + strconv.Itoa(*b.limit))
+	}
+
+	return Query{Text: sql.String(), Parameters: r.params}, nil
+}
+
+// Field resolves a Go field reference on T (e.g. "Address.City") to a SQL property reference (e.g. c.address.city).
+func Field[T any](field string) (string, error) {
+	path, err := operations.JSONPath[T](field)
+	if err != nil {
+		return "", err
+	}
+
+	var ref strings.Builder
+	ref.WriteString(alias)
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		switch {
+		case identifierPattern.MatchString(segment) && !reservedKeywords[strings.ToLower(segment)]:
+			ref.WriteString("." + segment)
+		case isIndex(segment):
+			ref.WriteString("[" + segment + "]")
+		default:
+			ref.WriteString("[" + strconv.Quote(segment) + "]")
+		}
+	}
+	return ref.String(), nil
+}
+
+func isIndex(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && strconv.Itoa(n) == s
+}
+
+// renderer tracks field resolution and parameters while a query is rendered.
+type renderer struct {
+	field  func(string) (string, error)
+	used   map[string]bool
+	params []azcosmos.QueryParameter
+	next   int
+}
+
+// bind registers value under a generated parameter name and returns the name.
+func (r *renderer) bind(value any) string {
+	for {
+		name := "@p" + strconv.Itoa(r.next)
+		r.next++
+		if !r.used[name] {
+			r.used[name] = true
+			r.params = append(r.params, azcosmos.QueryParameter{Name: name, Value: value})
+			return name
+		}
+	}
+}
+
+func (r *renderer) reserveName(name string) error {
+	if !paramNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid parameter name %q", name)
+	}
+	if r.used[name] {
+		return fmt.Errorf("duplicate parameter %q", name)
+	}
+	r.used[name] = true
+	return nil
+}
@@ -0,0 +1,139 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type task struct {
+	ID       string   `json:"id"`
+	Status   string   `json:"status"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags"`
+	Address  address  `json:"address"`
+	Owner    string   `json:"owner-name"`
+}
+
+func TestBuild_SelectAll(t *testing.T) {
+	q, err := New[task]().Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM c", q.Text)
+	assert.Empty(t, q.Parameters)
+}
+
+func TestBuild_Full(t *testing.T) {
+	q, err := New[task]().
+		Select("ID", "Address.City").
+		Where(Eq("Status", "open"), Gte("Priority", 2)).
+		Where(Or(ArrayContains("Tags", "urgent"), StartsWith("Address.City", "Sea"))).
+		OrderBy("Priority", Desc).
+		OrderBy("ID", Asc).
+		Offset(20, 10).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "SELECT c.id, c.address.city FROM c"+
+		" WHERE (c.status = @p0) AND (c.priority >= @p1) AND ((ARRAY_CONTAINS(c.tags, @p2)) OR (STARTSWITH(c.address.city, @p3)))"+
+		" ORDER BY c.priority DESC, c.id ASC OFFSET 20 LIMIT 10", q.Text)
+	assert.Equal(t, []azcosmos.QueryParameter{
+		{Name: "@p0", Value: "open"},
+		{Name: "@p1", Value: 2},
+		{Name: "@p2", Value: "urgent"},
+		{Name: "@p3", Value: "Sea"},
+	}, q.Parameters)
+}
+
+func TestBuild_TopAndCount(t *testing.T) {
+	q, err := New[task]().Top(5).Where(In("Status", "open", "blocked")).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT TOP 5 * FROM c WHERE c.status IN (@p0, @p1)", q.Text)
+
+	q, err = New[task]().Count().Where(Not(IsDefined("Tags"))).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT VALUE COUNT(1) FROM c WHERE NOT (IS_DEFINED(c.tags))", q.Text)
+}
+
+func TestBuild_Raw(t *testing.T) {
+	q, err := New[task]().
+		Where(Raw("c.priority > @p0 AND c._ts > @since", Param{"@p0", 3}, Param{"@since", 1700000000})).
+		Where(Eq("Status", "open")).
+		Build()
+	require.NoError(t, err)
+	// the generated parameter skips the name used by the raw condition
+	assert.Equal(t, "SELECT * FROM c WHERE (c.priority > @p0 AND c._ts > @since) AND (c.status = @p1)", q.Text)
+	assert.Len(t, q.Parameters, 3)
+}
+
+func TestBuild_Errors(t *testing.T) {
+	tests := map[string]*Builder[task]{
+		"unknown field":       New[task]().Where(Eq("Missing", 1)),
+		"top and offset":      New[task]().Top(1).Offset(0, 1),
+		"count and select":    New[task]().Count().Select("ID"),
+		"bad direction":       New[task]().OrderBy("ID", "SIDEWAYS"),
+		"empty in":            New[task]().Where(In("Status")),
+		"undeclared param":    New[task]().Where(Raw("c.status = @status")),
+		"duplicate param":     New[task]().Where(Raw("c.a = @x", Param{"@x", 1}), Raw("c.b = @x", Param{"@x", 2})),
+		"invalid param name":  New[task]().Where(Raw("c.a = @x", Param{"x", 1})),
+		"negative top":        New[task]().Top(-1),
+		"empty or":            New[task]().Where(Or()),
+		"unknown select":      New[task]().Select("Nope"),
+		"unknown order field": New[task]().OrderBy("Nope", Asc),
+	}
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := b.Build()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestField(t *testing.T) {
+	tests := map[string]string{
+		"ID":           "c.id",
+		"Address.City": "c.address.city",
+		"Tags.0":       "c.tags[0]",
+		"Owner":        `c["owner-name"]`,
+		"/_ts":         "c._ts",
+	}
+	for field, want := range tests {
+		got, err := Field[task](field)
+		require.NoError(t, err, field)
+		assert.Equal(t, want, got, field)
+	}
+}
+
+func TestField_Map(t *testing.T) {
+	got, err := Field[map[string]any]("info.title")
+	require.NoError(t, err)
+	assert.Equal(t, "c.info.title", got)
+}
+
+func TestField_ReservedKeyword(t *testing.T) {
+	got, err := Field[map[string]any]("value")
+	require.NoError(t, err)
+	assert.Equal(t, `c["value"]`, got)
+
+	got, err = Field[map[string]any]("Order.Top.total")
+	require.NoError(t, err)
+	assert.Equal(t, `c["Order"]["Top"].total`, got)
+}
+
+func TestQuery_Options(t *testing.T) {
+	q, err := New[task]().Where(Eq("Status", "open")).Build()
+	require.NoError(t, err)
+
+	base := &azcosmos.QueryOptions{PageSizeHint: 10}
+	opts := q.Options(base)
+	assert.Equal(t, int32(10), opts.PageSizeHint)
+	assert.Equal(t, q.Parameters, opts.QueryParameters)
+	assert.Empty(t, base.QueryParameters, "base options must not be modified")
+
+	assert.Equal(t, q.Parameters, q.Options(nil).QueryParameters)
+}
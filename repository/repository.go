@@ -0,0 +1,247 @@
+// Package repository provides a generic, typed repository over a Cosmos DB container,
+// composed from the helpers in the operations and query packages.
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/operations"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/query"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/retry"
+)
+
+// ErrNotFound is returned by FindOne when no item matches. It is cosmosdb_errors.ErrNotFound,
+// which the errors of Get match as well, so that callers check a single sentinel.
+var ErrNotFound = cosmosdb_errors.ErrNotFound
+
+// Repository provides typed CRUD and query operations for items of type T stored in a single container.
+type Repository[T any] struct {
+	container      *azcosmos.ContainerClient
+	idOf           func(T) (string, error)
+	partitionKeyOf func(T) (azcosmos.PartitionKey, error)
+	onCreate       func(*T, time.Time)
+	onUpdate       func(*T, time.Time)
+	now            func() time.Time
+	callOpts       []operations.CallOption
+}
+
+// Option configures a Repository.
+type Option[T any] func(*Repository[T])
+
+// WithID sets how the id of an item is determined. By default it is read from the item's "id" JSON property.
+func WithID[T any](idOf func(T) string) Option[T] {
+	return func(r *Repository[T]) {
+		r.idOf = func(item T) (string, error) { return idOf(item), nil }
+	}
+}
+
+// WithPartitionKey sets how the partition key of an item is determined.
+// By default it is derived from the item's `cosmos:"pk"` struct tags (see operations.PartitionKeyOf).
+func WithPartitionKey[T any](partitionKeyOf func(T) (azcosmos.PartitionKey, error)) Option[T] {
+	return func(r *Repository[T]) {
+		r.partitionKeyOf = partitionKeyOf
+	}
+}
+
+// WithCreateHook registers a function that is called with the current time before an item is created,
+// e.g. to set a CreatedAt timestamp.
+func WithCreateHook[T any](hook func(item *T, now time.Time)) Option[T] {
+	return func(r *Repository[T]) {
+		r.onCreate = hook
+	}
+}
+
+// WithUpdateHook registers a function that is called with the current time before an item is created,
+// upserted, replaced or updated, e.g. to set an UpdatedAt timestamp.
+func WithUpdateHook[T any](hook func(item *T, now time.Time)) Option[T] {
+	return func(r *Repository[T]) {
+		r.onUpdate = hook
+	}
+}
+
+// WithClock sets the time source passed to the hooks. Defaults to time.Now.
+func WithClock[T any](now func() time.Time) Option[T] {
+	return func(r *Repository[T]) {
+		r.now = now
+	}
+}
+
+// WithRetry retries the Cosmos DB calls of the repository that fail with transient or throttling errors,
+// using policy (see operations.WithRetry).
+func WithRetry[T any](policy *retry.Policy) Option[T] {
+	return func(r *Repository[T]) {
+		r.callOpts = append(r.callOpts, operations.WithRetry(policy))
+	}
+}
+
+// New returns a Repository for items of type T stored in container.
+func New[T any](container *azcosmos.ContainerClient, opts ...Option[T]) *Repository[T] {
+	r := &Repository[T]{
+		container:      container,
+		idOf:           idFromJSON[T],
+		partitionKeyOf: operations.PartitionKeyOf[T],
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Container returns the underlying container client.
+func (r *Repository[T]) Container() *azcosmos.ContainerClient {
+	return r.container
+}
+
+// Get reads a single item.
+func (r *Repository[T]) Get(ctx context.Context, id string, partitionKey azcosmos.PartitionKey) (T, error) {
+	return operations.GetItemCtx[T](ctx, r.container, id, partitionKey, nil, r.callOpts...)
+}
+
+// Exists reports whether an item exists.
+func (r *Repository[T]) Exists(ctx context.Context, id string, partitionKey azcosmos.PartitionKey) (bool, error) {
+	_, err := operations.GetItemCtx[json.RawMessage](ctx, r.container, id, partitionKey, nil, r.callOpts...)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, cosmosdb_errors.ErrNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Create inserts a new item and returns the stored item. It fails if an item with the same id already exists.
+func (r *Repository[T]) Create(ctx context.Context, item T) (T, error) {
+	now := r.now()
+	if r.onCreate != nil {
+		r.onCreate(&item, now)
+	}
+	if r.onUpdate != nil {
+		r.onUpdate(&item, now)
+	}
+	partitionKey, err := r.partitionKeyOf(item)
+	if err != nil {
+		return item, err
+	}
+	return operations.InsertItemWithResponseCtx(ctx, r.container, item, partitionKey, nil, r.callOpts...)
+}
+
+// Upsert creates or replaces an item and returns the stored item. Only the update hook is applied.
+func (r *Repository[T]) Upsert(ctx context.Context, item T) (T, error) {
+	if r.onUpdate != nil {
+		r.onUpdate(&item, r.now())
+	}
+	partitionKey, err := r.partitionKeyOf(item)
+	if err != nil {
+		return item, err
+	}
+	return operations.UpsertItemWithResponseCtx(ctx, r.container, item, partitionKey, nil, r.callOpts...)
+}
+
+// Replace replaces an existing item and returns the stored item.
+func (r *Repository[T]) Replace(ctx context.Context, item T) (T, error) {
+	if r.onUpdate != nil {
+		r.onUpdate(&item, r.now())
+	}
+	id, err := r.idOf(item)
+	if err != nil {
+		return item, err
+	}
+	partitionKey, err := r.partitionKeyOf(item)
+	if err != nil {
+		return item, err
+	}
+	return operations.ReplaceItemWithResponseCtx(ctx, r.container, id, partitionKey, item, nil, r.callOpts...)
+}
+
+// Update performs an optimistic-concurrency read-modify-write of an item (see operations.UpdateWithRetry).
+// The update hook is applied after mutate on every attempt.
+func (r *Repository[T]) Update(ctx context.Context, id string, partitionKey azcosmos.PartitionKey, mutate func(*T) error) (T, error) {
+	return operations.UpdateWithRetryCtx(ctx, r.container, id, partitionKey, func(item *T) error {
+		if err := mutate(item); err != nil {
+			return err
+		}
+		if r.onUpdate != nil {
+			r.onUpdate(item, r.now())
+		}
+		return nil
+	}, nil, r.callOpts...)
+}
+
+// Delete deletes an item. Deleting an item that does not exist is not an error.
+func (r *Repository[T]) Delete(ctx context.Context, id string, partitionKey azcosmos.PartitionKey) error {
+	return operations.DeleteItemIfExistsCtx(ctx, r.container, id, partitionKey, nil, r.callOpts...)
+}
+
+// Find returns all items in the partition that match the conditions (all items if there are none).
+// Use azcosmos.NewPartitionKey() to query across partitions.
+func (r *Repository[T]) Find(ctx context.Context, partitionKey azcosmos.PartitionKey, conditions ...query.Condition) ([]T, error) {
+	q, err := query.New[T]().Where(conditions...).Build()
+	if err != nil {
+		return nil, err
+	}
+	return operations.ExecuteQueryCtx[T](ctx, r.container, q.Text, partitionKey, q.Options(nil), r.callOpts...)
+}
+
+// FindOne returns the first item in the partition that matches the conditions, or ErrNotFound if there is none.
+func (r *Repository[T]) FindOne(ctx context.Context, partitionKey azcosmos.PartitionKey, conditions ...query.Condition) (T, error) {
+	var zero T
+	q, err := query.New[T]().Where(conditions...).Top(1).Build()
+	if err != nil {
+		return zero, err
+	}
+	items, err := operations.ExecuteQueryCtx[T](ctx, r.container, q.Text, partitionKey, q.Options(nil), r.callOpts...)
+	if err != nil {
+		return zero, err
+	}
+	if len(items) == 0 {
+		return zero, ErrNotFound
+	}
+	return items[0], nil
+}
+
+// Count returns the number of items in the partition that match the conditions.
+// The count must be scoped to a single partition key, since the Go SDK does not support cross-partition aggregates.
+func (r *Repository[T]) Count(ctx context.Context, partitionKey azcosmos.PartitionKey, conditions ...query.Condition) (int64, error) {
+	q, err := query.New[T]().Count().Where(conditions...).Build()
+	if err != nil {
+		return 0, err
+	}
+	counts, err := operations.ExecuteQueryCtx[int64](ctx, r.container, q.Text, partitionKey, q.Options(nil), r.callOpts...)
+	if err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, nil
+	}
+	return counts[0], nil
+}
+
+// List returns a single page of items in the partition, for paging through a container from an API endpoint.
+// Pass the ContinuationToken of the previous page to continue, or an empty string to start from the beginning.
+func (r *Repository[T]) List(ctx context.Context, partitionKey azcosmos.PartitionKey, pageSize int32, continuationToken string) (operations.QueryPage[T], error) {
+	return operations.ExecuteQueryPageCtx[T](ctx, r.container, "SELECT * FROM c", partitionKey, pageSize, continuationToken, nil, r.callOpts...)
+}
+
+// idFromJSON reads the "id" JSON property of an item.
+func idFromJSON[T any](item T) (string, error) {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	var doc struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", err
+	}
+	if doc.ID == "" {
+		return "", errors.New("item has no id")
+	}
+	return doc.ID, nil
+}
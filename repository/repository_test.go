@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/query"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type order struct {
+	ID         string    `json:"id"`
+	CustomerID string    `json:"customerId" cosmos:"pk"`
+	Total      int       `json:"total"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// memoryStore serves point operations on documents keyed by id.
+type memoryStore struct {
+	t    *testing.T
+	docs map[string]json.RawMessage
+	// queries records the body of every query request
+	queries []map[string]any
+	// results are returned for every query request
+	results any
+}
+
+func newMemoryStore(t *testing.T) *memoryStore {
+	return &memoryStore{t: t, docs: map[string]json.RawMessage{}}
+}
+
+func (s *memoryStore) handle(w http.ResponseWriter, r *http.Request) {
+	if cosmostest.IsQuery(r) {
+		var body map[string]any
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.queries = append(s.queries, body)
+		cosmostest.WriteDocuments(w, s.results, "")
+		return
+	}
+
+	id := path.Base(r.URL.Path)
+	switch r.Method {
+	case http.MethodGet:
+		doc, ok := s.docs[id]
+		if !ok {
+			cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "not found")
+			return
+		}
+		w.Header().Set("etag", `"1"`)
+		cosmostest.WriteJSON(w, http.StatusOK, doc)
+	case http.MethodPost, http.MethodPut:
+		var doc json.RawMessage
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&doc))
+		var meta struct {
+			ID string `json:"id"`
+		}
+		require.NoError(s.t, json.Unmarshal(doc, &meta))
+		_, exists := s.docs[meta.ID]
+		isUpsert := r.Header.Get("x-ms-documentdb-is-upsert") == "true"
+		if r.Method == http.MethodPost && exists && !isUpsert {
+			cosmostest.WriteError(w, http.StatusConflict, "Conflict", "exists")
+			return
+		}
+		if r.Method == http.MethodPut && !exists {
+			cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "not found")
+			return
+		}
+		s.docs[meta.ID] = doc
+		cosmostest.WriteJSON(w, http.StatusOK, doc)
+	case http.MethodDelete:
+		if _, ok := s.docs[id]; !ok {
+			cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "not found")
+			return
+		}
+		delete(s.docs, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+var fixedNow = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newOrderRepository(t *testing.T, store *memoryStore) *Repository[order] {
+	return New(cosmostest.NewContainer(t, store.handle),
+		WithCreateHook(func(o *order, now time.Time) { o.CreatedAt = now }),
+		WithUpdateHook(func(o *order, now time.Time) { o.UpdatedAt = now }),
+		WithClock[order](func() time.Time { return fixedNow }),
+	)
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore(t)
+	repo := newOrderRepository(t, store)
+	pk := azcosmos.NewPartitionKeyString("c1")
+
+	created, err := repo.Create(ctx, order{ID: "o1", CustomerID: "c1", Total: 10})
+	require.NoError(t, err)
+	assert.Equal(t, fixedNow, created.CreatedAt)
+	assert.Equal(t, fixedNow, created.UpdatedAt)
+
+	_, err = repo.Create(ctx, order{ID: "o1", CustomerID: "c1"})
+	assert.Error(t, err)
+
+	exists, err := repo.Exists(ctx, "o1", pk)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	got, err := repo.Get(ctx, "o1", pk)
+	require.NoError(t, err)
+	assert.Equal(t, 10, got.Total)
+
+	got.Total = 20
+	replaced, err := repo.Replace(ctx, got)
+	require.NoError(t, err)
+	assert.Equal(t, 20, replaced.Total)
+
+	updated, err := repo.Update(ctx, "o1", pk, func(o *order) error {
+		o.Total++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 21, updated.Total)
+
+	upserted, err := repo.Upsert(ctx, order{ID: "o2", CustomerID: "c1"})
+	require.NoError(t, err)
+	assert.True(t, upserted.CreatedAt.IsZero(), "upsert applies the update hook only")
+	assert.Equal(t, fixedNow, upserted.UpdatedAt)
+
+	require.NoError(t, repo.Delete(ctx, "o1", pk))
+	require.NoError(t, repo.Delete(ctx, "o1", pk), "deleting a missing item is not an error")
+
+	exists, err = repo.Exists(ctx, "o1", pk)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestRepository_CustomExtractors(t *testing.T) {
+	type note struct {
+		Key  string `json:"id"`
+		Text string `json:"text"`
+	}
+	store := newMemoryStore(t)
+	repo := New(cosmostest.NewContainer(t, store.handle),
+		WithID(func(n note) string { return n.Key }),
+		WithPartitionKey(func(n note) (azcosmos.PartitionKey, error) { return azcosmos.NewPartitionKeyString(n.Key), nil }),
+	)
+
+	_, err := repo.Create(context.Background(), note{Key: "n1", Text: "hello"})
+	require.NoError(t, err)
+	_, err = repo.Replace(context.Background(), note{Key: "n1", Text: "bye"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"n1","text":"bye"}`, string(store.docs["n1"]))
+}
+
+func TestRepository_WithRetry(t *testing.T) {
+	store := newMemoryStore(t)
+	store.docs["o1"] = json.RawMessage(`{"id":"o1","customerId":"c1","total":10}`)
+	throttled := 0
+	repo := New(cosmostest.NewContainer(t, func(w http.ResponseWriter, r *http.Request) {
+		if throttled < 2 {
+			throttled++
+			w.Header().Set("x-ms-retry-after-ms", "1")
+			cosmostest.WriteError(w, http.StatusTooManyRequests, "TooManyRequests", "throttled")
+			return
+		}
+		store.handle(w, r)
+	}), WithRetry[order](&retry.Policy{MaxAttempts: 3}))
+
+	got, err := repo.Get(context.Background(), "o1", azcosmos.NewPartitionKeyString("c1"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, got.Total)
+	assert.Equal(t, 2, throttled)
+}
+
+func TestRepository_Replace_NoID(t *testing.T) {
+	repo := New[order](cosmostest.NewContainer(t, cosmostest.Unexpected(t)))
+
+	_, err := repo.Replace(context.Background(), order{CustomerID: "c1"})
+	assert.ErrorContains(t, err, "no id")
+}
+
+func TestRepository_Find(t *testing.T) {
+	store := newMemoryStore(t)
+	store.results = []order{{ID: "o1", Total: 50}, {ID: "o2", Total: 70}}
+	repo := newOrderRepository(t, store)
+
+	orders, err := repo.Find(context.Background(), azcosmos.NewPartitionKeyString("c1"), query.Gt("Total", 40))
+	require.NoError(t, err)
+	assert.Len(t, orders, 2)
+
+	require.Len(t, store.queries, 1)
+	assert.Equal(t, "SELECT * FROM c WHERE c.total > @p0", store.queries[0]["query"])
+	assert.Equal(t, []any{map[string]any{"name": "@p0", "value": float64(40)}}, store.queries[0]["parameters"])
+}
+
+func TestRepository_FindOne(t *testing.T) {
+	store := newMemoryStore(t)
+	repo := newOrderRepository(t, store)
+
+	_, err := repo.FindOne(context.Background(), azcosmos.NewPartitionKeyString("c1"), query.Eq("ID", "o9"))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, cosmosdb_errors.ErrNotFound)
+	assert.Equal(t, "SELECT TOP 1 * FROM c WHERE c.id = @p0", store.queries[0]["query"])
+}
+
+func TestRepository_Count(t *testing.T) {
+	store := newMemoryStore(t)
+	store.results = []int64{3}
+	repo := newOrderRepository(t, store)
+
+	n, err := repo.Count(context.Background(), azcosmos.NewPartitionKeyString("c1"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+	assert.Equal(t, "SELECT VALUE COUNT(1) FROM c", store.queries[0]["query"])
+}
+
+func TestRepository_List(t *testing.T) {
+	store := newMemoryStore(t)
+	store.results = []order{{ID: "o1"}}
+	repo := newOrderRepository(t, store)
+
+	page, err := repo.List(context.Background(), azcosmos.NewPartitionKeyString("c1"), 10, "")
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Empty(t, page.ContinuationToken)
+}
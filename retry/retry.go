@@ -0,0 +1,86 @@
+// Package retry retries Cosmos DB calls that fail with transient or throttling errors,
+// using exponential backoff with jitter and honouring the retry-after interval provided by the service.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+const (
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+)
+
+// Policy configures Do. The zero value is usable and applies the defaults.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Defaults to 5.
+	MaxAttempts int
+	// InitialBackoff is the upper bound of the delay before the first retry. It doubles with every retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff. Defaults to 5s. It does not cap a retry-after interval provided by the service.
+	MaxBackoff time.Duration
+	// Retryable decides whether an error is retried. Defaults to cosmosdb_errors.IsRetryable.
+	Retryable func(error) bool
+	// OnRetry, if set, is called before waiting for the next attempt, e.g. for logging.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Do calls fn until it succeeds, returns an error that is not retryable, or the policy's attempts are used up.
+// Between attempts it waits for the retry-after interval provided by the service (on 429, and on 503/410 responses
+// that carry one), or otherwise for an exponentially growing, fully jittered backoff.
+// The last error of fn is returned unchanged. A nil policy uses the defaults.
+func Do(ctx context.Context, policy *Policy, fn func(ctx context.Context) error) error {
+	_, err := DoValue(ctx, policy, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoValue is like Do for functions that also return a value.
+func DoValue[T any](ctx context.Context, policy *Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	p := Policy{}
+	if policy != nil {
+		p = *policy
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	if p.Retryable == nil {
+		p.Retryable = cosmosdb_errors.IsRetryable
+	}
+
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		value, err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.Retryable(err) {
+			return value, err
+		}
+
+		delay := cosmosdb_errors.GetError(err).RetryAfter
+		if delay <= 0 {
+			// full jitter keeps concurrent clients from retrying in lockstep
+			delay = time.Duration(rand.Int64N(int64(backoff))) + 1
+			backoff = min(backoff*2, p.MaxBackoff)
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return value, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
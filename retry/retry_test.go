@@ -0,0 +1,91 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func throttled(retryAfterMs string) error {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("x-ms-retry-after-ms", retryAfterMs)
+	return &azcore.ResponseError{StatusCode: http.StatusTooManyRequests, RawResponse: resp}
+}
+
+func TestDo_RetriesUntilSuccess(t *testing.T) {
+	var delays []time.Duration
+	policy := &Policy{OnRetry: func(attempt int, err error, delay time.Duration) { delays = append(delays, delay) }}
+
+	attempts := 0
+	err := Do(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return throttled("5")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []time.Duration{5 * time.Millisecond, 5 * time.Millisecond}, delays, "retry-after is honoured")
+}
+
+func TestDo_Backoff(t *testing.T) {
+	var delays []time.Duration
+	policy := &Policy{
+		MaxAttempts:    4,
+		InitialBackoff: 4 * time.Millisecond,
+		MaxBackoff:     8 * time.Millisecond,
+		OnRetry:        func(attempt int, err error, delay time.Duration) { delays = append(delays, delay) },
+	}
+	unavailable := &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+
+	attempts := 0
+	err := Do(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return unavailable
+	})
+	assert.Same(t, unavailable, err)
+	assert.Equal(t, 4, attempts)
+	require.Len(t, delays, 3)
+	for i, max := range []time.Duration{4, 8, 8} {
+		assert.LessOrEqual(t, delays[i], max*time.Millisecond)
+	}
+}
+
+func TestDo_NotRetryable(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), nil, func(ctx context.Context) error {
+		attempts++
+		return &azcore.ResponseError{StatusCode: http.StatusBadRequest}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDo_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Do(ctx, nil, func(ctx context.Context) error {
+		cancel()
+		return throttled("60000")
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDoValue(t *testing.T) {
+	value, err := DoValue(context.Background(), &Policy{Retryable: func(err error) bool { return true }}, func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, value)
+
+	_, err = DoValue(context.Background(), &Policy{MaxAttempts: 1}, func(ctx context.Context) (int, error) {
+		return 0, errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+}
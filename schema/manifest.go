@@ -0,0 +1,264 @@
+// Package schema provisions databases and containers from a declarative YAML or JSON manifest,
+// so that an application converges its Cosmos DB account to the same schema in every environment.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes the databases and containers of an account.
+//
+//	databases:
+//	  - id: shop
+//	    throughput: {autoscaleMax: 4000}
+//	    containers:
+//	      - id: orders
+//	        partitionKey: /customerId
+//	        defaultTtl: 2592000
+//	        uniqueKeys: [[/orderNumber]]
+//	        indexingPolicy:
+//	          indexingMode: consistent
+//	          includedPaths: [{path: /*}]
+//	          excludedPaths: [{path: /payload/*}]
+//	        storedProcedures:
+//	          - {id: bulkDelete, file: sprocs/bulkDelete.js}
+type Manifest struct {
+	Databases []Database `json:"databases"`
+}
+
+// Database describes a database and its containers.
+type Database struct {
+	ID string `json:"id"`
+	// Throughput is the shared throughput of the database. Nil means that only containers have throughput.
+	Throughput *Throughput `json:"throughput,omitempty"`
+	Containers []Container `json:"containers,omitempty"`
+}
+
+// Throughput describes provisioned throughput. Exactly one of Manual and AutoscaleMax must be set.
+type Throughput struct {
+	// Manual is the manually provisioned throughput in RU/s.
+	Manual int32 `json:"manual,omitempty"`
+	// AutoscaleMax is the maximum throughput in RU/s of autoscale throughput.
+	AutoscaleMax int32 `json:"autoscaleMax,omitempty"`
+}
+
+// Properties returns the SDK representation of t.
+func (t Throughput) Properties() azcosmos.ThroughputProperties {
+	if t.AutoscaleMax > 0 {
+		return azcosmos.NewAutoscaleThroughputProperties(t.AutoscaleMax)
+	}
+	return azcosmos.NewManualThroughputProperties(t.Manual)
+}
+
+func (t Throughput) String() string {
+	if t.AutoscaleMax > 0 {
+		return fmt.Sprintf("autoscale max %d RU/s", t.AutoscaleMax)
+	}
+	return fmt.Sprintf("manual %d RU/s", t.Manual)
+}
+
+// Container describes a container.
+type Container struct {
+	ID string `json:"id"`
+	// PartitionKey holds the partition key paths. A single path can be written as a string;
+	// two or three paths define a hierarchical partition key.
+	PartitionKey PartitionKeyPaths `json:"partitionKey"`
+	// DefaultTTL is the default time to live of items in seconds. -1 enables TTL without expiring items by default.
+	DefaultTTL *int32 `json:"defaultTtl,omitempty"`
+	// IndexingPolicy uses the JSON format of the Cosmos DB indexing policy. Nil keeps the service default.
+	IndexingPolicy *azcosmos.IndexingPolicy `json:"indexingPolicy,omitempty"`
+	// UniqueKeys lists the unique keys of the container, each as a set of paths.
+	UniqueKeys               [][]string                         `json:"uniqueKeys,omitempty"`
+	ConflictResolutionPolicy *azcosmos.ConflictResolutionPolicy `json:"conflictResolutionPolicy,omitempty"`
+	// Throughput is the dedicated throughput of the container. Nil means that the container uses the database's throughput.
+	Throughput       *Throughput       `json:"throughput,omitempty"`
+	StoredProcedures []StoredProcedure `json:"storedProcedures,omitempty"`
+}
+
+// Properties returns the SDK representation of c.
+func (c Container) Properties() azcosmos.ContainerProperties {
+	props := azcosmos.ContainerProperties{
+		ID: c.ID,
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+			Kind:  azcosmos.PartitionKeyKindHash,
+			Paths: c.PartitionKey,
+		},
+		DefaultTimeToLive:        c.DefaultTTL,
+		ConflictResolutionPolicy: c.ConflictResolutionPolicy,
+	}
+	if len(c.PartitionKey) > 1 {
+		props.PartitionKeyDefinition.Kind = azcosmos.PartitionKeyKindMultiHash
+		props.PartitionKeyDefinition.Version = 2
+	}
+	if c.IndexingPolicy != nil {
+		policy := *c.IndexingPolicy
+		// automatic has no omitempty in the SDK, and manual indexing is no longer supported by the service
+		policy.Automatic = !strings.EqualFold(string(policy.IndexingMode), string(azcosmos.IndexingModeNone))
+		props.IndexingPolicy = &policy
+	}
+	if len(c.UniqueKeys) > 0 {
+		props.UniqueKeyPolicy = &azcosmos.UniqueKeyPolicy{}
+		for _, paths := range c.UniqueKeys {
+			props.UniqueKeyPolicy.UniqueKeys = append(props.UniqueKeyPolicy.UniqueKeys, azcosmos.UniqueKey{Paths: paths})
+		}
+	}
+	return props
+}
+
+// PartitionKeyPaths holds partition key paths. It unmarshals from a single string or a list of strings.
+type PartitionKeyPaths []string
+
+func (p *PartitionKeyPaths) UnmarshalJSON(b []byte) error {
+	var path string
+	if err := json.Unmarshal(b, &path); err == nil {
+		*p = PartitionKeyPaths{path}
+		return nil
+	}
+	var paths []string
+	if err := json.Unmarshal(b, &paths); err != nil {
+		return fmt.Errorf("partition key must be a path or a list of paths: %w", err)
+	}
+	*p = paths
+	return nil
+}
+
+// StoredProcedure describes a stored procedure. Exactly one of Body and File must be set.
+type StoredProcedure struct {
+	ID   string `json:"id"`
+	Body string `json:"body,omitempty"`
+	// File is the path of a .js file with the body. LoadManifest resolves it relative to the manifest.
+	File string `json:"file,omitempty"`
+}
+
+// body returns the body of the stored procedure, reading it from File if needed.
+func (s StoredProcedure) body() (string, error) {
+	if s.File == "" {
+		return s.Body, nil
+	}
+	b, err := os.ReadFile(s.File)
+	if err != nil {
+		return "", fmt.Errorf("stored procedure %q: %w", s.ID, err)
+	}
+	return string(b), nil
+}
+
+// ParseManifest parses and validates a YAML or JSON manifest. Unknown fields are rejected.
+func ParseManifest(data []byte) (*Manifest, error) {
+	// JSON is a subset of YAML. Converting the YAML document to JSON reuses the JSON tags of the SDK types.
+	var document any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	var m Manifest
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// LoadManifest reads a manifest file with ParseManifest. Stored procedure files are resolved relative to its directory.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i := range m.Databases {
+		for j := range m.Databases[i].Containers {
+			for k, sproc := range m.Databases[i].Containers[j].StoredProcedures {
+				if sproc.File != "" && !filepath.IsAbs(sproc.File) {
+					m.Databases[i].Containers[j].StoredProcedures[k].File = filepath.Join(dir, sproc.File)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// Validate reports all problems of the manifest that would make provisioning fail.
+func (m *Manifest) Validate() error {
+	var errs []error
+	databases := map[string]bool{}
+	for _, db := range m.Databases {
+		if db.ID == "" {
+			errs = append(errs, errors.New("database without id"))
+		} else if databases[db.ID] {
+			errs = append(errs, fmt.Errorf("database %q is declared twice", db.ID))
+		}
+		databases[db.ID] = true
+		errs = append(errs, db.Throughput.validate("database "+db.ID))
+
+		containers := map[string]bool{}
+		for _, c := range db.Containers {
+			name := fmt.Sprintf("container %s/%s", db.ID, c.ID)
+			if c.ID == "" {
+				errs = append(errs, fmt.Errorf("container without id in database %q", db.ID))
+			} else if containers[c.ID] {
+				errs = append(errs, fmt.Errorf("%s is declared twice", name))
+			}
+			containers[c.ID] = true
+
+			if len(c.PartitionKey) == 0 || len(c.PartitionKey) > 3 {
+				errs = append(errs, fmt.Errorf("%s: partition key must have one to three paths", name))
+			}
+			for _, path := range c.PartitionKey {
+				if !strings.HasPrefix(path, "/") {
+					errs = append(errs, fmt.Errorf("%s: partition key path %q must start with /", name, path))
+				}
+			}
+			for _, paths := range c.UniqueKeys {
+				if len(paths) == 0 {
+					errs = append(errs, fmt.Errorf("%s: unique key without paths", name))
+				}
+			}
+			errs = append(errs, c.Throughput.validate(name))
+
+			sprocs := map[string]bool{}
+			for _, sproc := range c.StoredProcedures {
+				if sproc.ID == "" {
+					errs = append(errs, fmt.Errorf("%s: stored procedure without id", name))
+				} else if sprocs[sproc.ID] {
+					errs = append(errs, fmt.Errorf("%s: stored procedure %q is declared twice", name, sproc.ID))
+				}
+				sprocs[sproc.ID] = true
+				if (sproc.Body == "") == (sproc.File == "") {
+					errs = append(errs, fmt.Errorf("%s: stored procedure %q must have either a body or a file", name, sproc.ID))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Throughput) validate(name string) error {
+	if t == nil {
+		return nil
+	}
+	if (t.Manual > 0) == (t.AutoscaleMax > 0) {
+		return fmt.Errorf("%s: throughput must set either manual or autoscaleMax", name)
+	}
+	return nil
+}
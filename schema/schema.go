@@ -0,0 +1,239 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/common"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+// StoredProcedureDeployer creates or replaces stored procedures. The Go SDK has no stored procedure API,
+// so EnsureSchema deploys them through this interface.
+type StoredProcedureDeployer interface {
+	// EnsureStoredProcedure creates the stored procedure, or replaces it if its body differs,
+	// and reports whether it changed anything.
+	EnsureStoredProcedure(ctx context.Context, database, container, id, body string) (bool, error)
+}
+
+// EnsureOptions configures EnsureSchema.
+type EnsureOptions struct {
+	// StoredProcedures deploys the stored procedures of the manifest. It is required if the manifest declares any.
+	StoredProcedures StoredProcedureDeployer
+	// Reconcile updates the properties of existing containers that differ from the manifest,
+	// using common.ReconcileContainer. Changes to immutable properties, such as the partition key, fail.
+	Reconcile bool
+	// MigrateThroughput switches existing databases and containers between manual and autoscale throughput.
+	// Without it, a changed throughput mode fails. See common.MigrateFunc.
+	MigrateThroughput common.MigrateFunc
+}
+
+// Change describes a change made by EnsureSchema.
+type Change struct {
+	// Resource is the path of the changed resource, e.g. "dbs/shop/colls/orders".
+	Resource    string
+	Description string
+}
+
+func (c Change) String() string {
+	return c.Resource + ": " + c.Description
+}
+
+// Result lists the changes made by EnsureSchema. It is empty if the account already matched the manifest.
+type Result struct {
+	Changes []Change
+}
+
+func (r *Result) add(resource, format string, args ...any) {
+	r.Changes = append(r.Changes, Change{Resource: resource, Description: fmt.Sprintf(format, args...)})
+}
+
+// EnsureSchema converges the account to the manifest: missing databases and containers are created
+// with the declared properties and throughput, the throughput of existing ones is updated,
+// and stored procedures are deployed. Running it again against an account that matches the manifest changes nothing.
+//
+// Properties of existing containers are only changed if EnsureOptions.Reconcile is set.
+// Resources that are not in the manifest are left alone.
+// Throughput cannot be added to an existing database or container that was created without it,
+// and switching between manual and autoscale throughput requires EnsureOptions.MigrateThroughput.
+func EnsureSchema(client *azcosmos.Client, m *Manifest, opts *EnsureOptions) (*Result, error) {
+	return EnsureSchemaCtx(context.Background(), client, m, opts)
+}
+
+// EnsureSchemaCtx is like EnsureSchema but uses the provided context for all Cosmos DB calls.
+func EnsureSchemaCtx(ctx context.Context, client *azcosmos.Client, m *Manifest, opts *EnsureOptions) (*Result, error) {
+	o := EnsureOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if o.StoredProcedures == nil {
+		for _, db := range m.Databases {
+			for _, c := range db.Containers {
+				if len(c.StoredProcedures) > 0 {
+					return nil, fmt.Errorf("container %s/%s declares stored procedures, but EnsureOptions.StoredProcedures is not set", db.ID, c.ID)
+				}
+			}
+		}
+	}
+
+	result := &Result{}
+	for _, spec := range m.Databases {
+		if err := ensureDatabase(ctx, client, spec, o, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func ensureDatabase(ctx context.Context, client *azcosmos.Client, spec Database, o EnsureOptions, result *Result) error {
+	resource := "dbs/" + spec.ID
+	db, err := client.NewDatabase(spec.ID)
+	if err != nil {
+		return err
+	}
+
+	exists, err := exists(db.Read(ctx, nil))
+	if err != nil {
+		return operationError("ReadDatabase", spec.ID, "", err)
+	}
+	createOpts := &azcosmos.CreateDatabaseOptions{}
+	if spec.Throughput != nil {
+		throughput := spec.Throughput.Properties()
+		createOpts.ThroughputProperties = &throughput
+	}
+	db, err = common.CreateDatabaseIfNotExistsCtx(ctx, client, azcosmos.DatabaseProperties{ID: spec.ID}, createOpts)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		result.add(resource, "created database")
+	} else if spec.Throughput != nil {
+		if err := ensureThroughput(ctx, *spec.Throughput, db, resource, o, result); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range spec.Containers {
+		if err := ensureContainer(ctx, db, c, o, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureContainer(ctx context.Context, db *azcosmos.DatabaseClient, spec Container, o EnsureOptions, result *Result) error {
+	resource := "dbs/" + db.ID() + "/colls/" + spec.ID
+	container, err := db.NewContainer(spec.ID)
+	if err != nil {
+		return err
+	}
+
+	exists, err := exists(container.Read(ctx, nil))
+	if err != nil {
+		return operationError("ReadContainer", db.ID(), spec.ID, err)
+	}
+	createOpts := &azcosmos.CreateContainerOptions{}
+	if spec.Throughput != nil {
+		throughput := spec.Throughput.Properties()
+		createOpts.ThroughputProperties = &throughput
+	}
+	if o.Reconcile {
+		var diff common.ContainerDiff
+		container, diff, err = common.ReconcileContainerCtx(ctx, db, spec.Properties(), &common.ReconcileOptions{CreateOptions: createOpts})
+		if err != nil {
+			return err
+		}
+		for _, change := range diff.Changes {
+			result.add(resource, "changed %s", change)
+		}
+	} else {
+		container, err = common.CreateContainerIfNotExistsCtx(ctx, db, spec.Properties(), createOpts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !exists {
+		result.add(resource, "created container")
+	} else if spec.Throughput != nil {
+		if err := ensureThroughput(ctx, *spec.Throughput, container, resource, o, result); err != nil {
+			return err
+		}
+	}
+
+	for _, sproc := range spec.StoredProcedures {
+		body, err := sproc.body()
+		if err != nil {
+			return err
+		}
+		changed, err := o.StoredProcedures.EnsureStoredProcedure(ctx, db.ID(), spec.ID, sproc.ID, body)
+		if err != nil {
+			return err
+		}
+		if changed {
+			result.add(resource+"/sprocs/"+sproc.ID, "deployed stored procedure")
+		}
+	}
+	return nil
+}
+
+// ensureThroughput changes the throughput of an existing database or container if it differs from the manifest.
+func ensureThroughput(ctx context.Context, spec Throughput, target common.ThroughputResource, resource string, o EnsureOptions, result *Result) error {
+	current, err := common.GetThroughputCtx(ctx, target)
+	if err != nil {
+		if errors.Is(err, cosmosdb_errors.ErrNotFound) {
+			return fmt.Errorf("%s has no dedicated throughput; throughput can only be provisioned when it is created", resource)
+		}
+		return err
+	}
+
+	mode, throughput := common.ThroughputManual, spec.Manual
+	if spec.AutoscaleMax > 0 {
+		mode, throughput = common.ThroughputAutoscale, spec.AutoscaleMax
+	}
+	if current.Mode == mode && current.Throughput == throughput {
+		return nil
+	}
+	if current.Mode != mode && o.MigrateThroughput == nil {
+		return fmt.Errorf("%s has %s, switching to %s requires EnsureOptions.MigrateThroughput", resource, current, spec)
+	}
+
+	scaleOpts := &common.ScaleOptions{Migrate: o.MigrateThroughput}
+	if mode == common.ThroughputAutoscale {
+		_, err = common.SwitchToAutoscaleThroughputCtx(ctx, target, throughput, scaleOpts)
+	} else {
+		_, err = common.SwitchToManualThroughputCtx(ctx, target, throughput, scaleOpts)
+	}
+	if err != nil {
+		return err
+	}
+	result.add(resource, "changed throughput from %s to %s", current, spec)
+	return nil
+}
+
+// exists reports whether a read found the resource.
+func exists[R any](_ R, err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// operationError wraps err in a cosmosdb_errors.OperationError for an operation on a database or container.
+func operationError(operation, database, container string, err error) error {
+	return cosmosdb_errors.NewOperationError(cosmosdb_errors.OperationError{
+		Operation: operation,
+		Database:  database,
+		Container: container,
+		Err:       err,
+	})
+}
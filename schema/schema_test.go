@@ -0,0 +1,204 @@
+package schema
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/common"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `
+databases:
+  - id: shop
+    throughput: {manual: 400}
+    containers:
+      - id: orders
+        partitionKey: /customerId
+        defaultTtl: -1
+        uniqueKeys: [[/orderNumber]]
+        indexingPolicy:
+          indexingMode: consistent
+          excludedPaths: [{path: /payload/*}]
+      - id: events
+        partitionKey: [/tenantId, /userId]
+        throughput: {autoscaleMax: 4000}
+        storedProcedures:
+          - {id: archive, body: "function archive() {}"}
+`
+
+type fakeDeployer map[string]string
+
+func (d fakeDeployer) EnsureStoredProcedure(_ context.Context, database, container, id, body string) (bool, error) {
+	key := database + "/" + container + "/" + id
+	if d[key] == body {
+		return false, nil
+	}
+	d[key] = body
+	return true, nil
+}
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(testManifest))
+	require.NoError(t, err)
+
+	orders := m.Databases[0].Containers[0].Properties()
+	assert.Equal(t, azcosmos.PartitionKeyDefinition{Kind: azcosmos.PartitionKeyKindHash, Paths: []string{"/customerId"}}, orders.PartitionKeyDefinition)
+	assert.Equal(t, int32(-1), *orders.DefaultTimeToLive)
+	assert.Equal(t, []azcosmos.UniqueKey{{Paths: []string{"/orderNumber"}}}, orders.UniqueKeyPolicy.UniqueKeys)
+	assert.True(t, orders.IndexingPolicy.Automatic)
+	assert.Equal(t, []azcosmos.ExcludedPath{{Path: "/payload/*"}}, orders.IndexingPolicy.ExcludedPaths)
+
+	events := m.Databases[0].Containers[1].Properties()
+	assert.Equal(t, azcosmos.PartitionKeyDefinition{Kind: azcosmos.PartitionKeyKindMultiHash, Paths: []string{"/tenantId", "/userId"}, Version: 2}, events.PartitionKeyDefinition)
+
+	// JSON manifests are parsed the same way
+	_, err = ParseManifest([]byte(`{"databases": [{"id": "shop", "containers": [{"id": "orders", "partitionKey": "/customerId"}]}]}`))
+	require.NoError(t, err)
+}
+
+func TestParseManifest_Invalid(t *testing.T) {
+	_, err := ParseManifest([]byte(`
+databases:
+  - id: shop
+    throughput: {manual: 400, autoscaleMax: 4000}
+    containers:
+      - id: orders
+        partitionKey: customerId
+      - id: orders
+        partitionKey: [/a, /b, /c, /d]
+`))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "database shop: throughput must set either manual or autoscaleMax")
+	assert.ErrorContains(t, err, `partition key path "customerId" must start with /`)
+	assert.ErrorContains(t, err, "container shop/orders is declared twice")
+	assert.ErrorContains(t, err, "partition key must have one to three paths")
+
+	_, err = ParseManifest([]byte("databases:\n  - id: shop\n    containerz: []\n"))
+	assert.ErrorContains(t, err, `unknown field "containerz"`)
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sprocs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sprocs", "archive.js"), []byte("function archive() {}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schema.yaml"), []byte(`
+databases:
+  - id: shop
+    containers:
+      - id: events
+        partitionKey: /tenantId
+        storedProcedures: [{id: archive, file: sprocs/archive.js}]
+`), 0o644))
+
+	m, err := LoadManifest(filepath.Join(dir, "schema.yaml"))
+	require.NoError(t, err)
+	body, err := m.Databases[0].Containers[0].StoredProcedures[0].body()
+	require.NoError(t, err)
+	assert.Equal(t, "function archive() {}", body)
+}
+
+func TestEnsureSchema(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	m, err := ParseManifest([]byte(testManifest))
+	require.NoError(t, err)
+	deployer := fakeDeployer{}
+
+	result, err := EnsureSchema(client, m, &EnsureOptions{StoredProcedures: deployer})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Resource: "dbs/shop", Description: "created database"},
+		{Resource: "dbs/shop/colls/orders", Description: "created container"},
+		{Resource: "dbs/shop/colls/events", Description: "created container"},
+		{Resource: "dbs/shop/colls/events/sprocs/archive", Description: "deployed stored procedure"},
+	}, result.Changes)
+
+	events, err := client.NewContainer("shop", "events")
+	require.NoError(t, err)
+	throughput, err := events.ReadThroughput(context.Background(), nil)
+	require.NoError(t, err)
+	maxThroughput, ok := throughput.ThroughputProperties.AutoscaleMaxThroughput()
+	assert.True(t, ok)
+	assert.Equal(t, int32(4000), maxThroughput)
+
+	// a second run converges without writes
+	writes := len(account.Writes())
+	result, err = EnsureSchema(client, m, &EnsureOptions{StoredProcedures: deployer})
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+	assert.Len(t, account.Writes(), writes)
+
+	// changed throughput is applied to the existing resources
+	m.Databases[0].Throughput.Manual = 1000
+	m.Databases[0].Containers[1].Throughput.AutoscaleMax = 10000
+	result, err = EnsureSchema(client, m, &EnsureOptions{StoredProcedures: deployer})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Resource: "dbs/shop", Description: "changed throughput from manual 400 RU/s to manual 1000 RU/s"},
+		{Resource: "dbs/shop/colls/events", Description: "changed throughput from autoscale max 4000 RU/s to autoscale max 10000 RU/s"},
+	}, result.Changes)
+}
+
+func TestEnsureSchema_Errors(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	m, err := ParseManifest([]byte(testManifest))
+	require.NoError(t, err)
+
+	_, err = EnsureSchema(client, m, nil)
+	assert.ErrorContains(t, err, "container shop/events declares stored procedures, but EnsureOptions.StoredProcedures is not set")
+	assert.Empty(t, account.Requests())
+
+	m.Databases[0].Containers[1].StoredProcedures = nil
+	_, err = EnsureSchema(client, m, nil)
+	require.NoError(t, err)
+
+	m.Databases[0].Containers[1].Throughput = &Throughput{Manual: 400}
+	_, err = EnsureSchema(client, m, nil)
+	assert.EqualError(t, err, "dbs/shop/colls/events has autoscale max 4000 RU/s, switching to manual 400 RU/s requires EnsureOptions.MigrateThroughput")
+
+	// the data plane cannot migrate offers, so the fake migrates by replacing the offer
+	migrate := func(ctx context.Context, resource common.ThroughputResource, to common.ThroughputMode) error {
+		assert.Equal(t, common.ThroughputManual, to)
+		_, err := resource.ReplaceThroughput(ctx, azcosmos.NewManualThroughputProperties(4000), nil)
+		return err
+	}
+	result, err := EnsureSchema(client, m, &EnsureOptions{MigrateThroughput: migrate})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Resource: "dbs/shop/colls/events", Description: "changed throughput from autoscale max 4000 RU/s to manual 400 RU/s"}}, result.Changes)
+
+	m.Databases[0].Containers[0].Throughput = &Throughput{Manual: 400}
+	_, err = EnsureSchema(client, m, nil)
+	assert.EqualError(t, err, "dbs/shop/colls/orders has no dedicated throughput; throughput can only be provisioned when it is created")
+}
+
+func TestEnsureSchema_Reconcile(t *testing.T) {
+	account := cosmostest.NewAccount()
+	client := cosmostest.NewClient(t, account.Handle)
+	m, err := ParseManifest([]byte("databases:\n  - id: shop\n    containers:\n      - {id: orders, partitionKey: /customerId}\n"))
+	require.NoError(t, err)
+	_, err = EnsureSchema(client, m, nil)
+	require.NoError(t, err)
+
+	ttl := int32(3600)
+	m.Databases[0].Containers[0].DefaultTTL = &ttl
+
+	// without Reconcile, properties of existing containers are left alone
+	result, err := EnsureSchema(client, m, nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Changes)
+
+	result, err = EnsureSchema(client, m, &EnsureOptions{Reconcile: true})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Resource: "dbs/shop/colls/orders", Description: "changed defaultTtl: none -> 3600"}}, result.Changes)
+
+	m.Databases[0].Containers[0].PartitionKey = PartitionKeyPaths{"/tenantId"}
+	_, err = EnsureSchema(client, m, &EnsureOptions{Reconcile: true})
+	assert.ErrorIs(t, err, common.ErrImmutableProperty)
+}
@@ -0,0 +1,154 @@
+// Package scripts deploys and executes Cosmos DB server-side JavaScript: stored procedures,
+// user-defined functions and triggers.
+//
+// The Go SDK has no API for server-side scripts, and the pipeline of azcosmos.Client is not exported,
+// so Client sends the REST requests itself, through an azcore pipeline that authenticates like the SDK.
+package scripts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+const (
+	moduleName = "cosmosdb-go-sdk-helper/scripts"
+	modulePath = "github.com/abhirockzz/cosmosdb-go-sdk-helper"
+	apiVersion = "2020-11-05"
+
+	headerDate          = "x-ms-date"
+	headerVersion       = "x-ms-version"
+	headerAuthorization = "Authorization"
+	headerPartitionKey  = "x-ms-documentdb-partitionkey"
+)
+
+// Client sends requests for server-side scripts to a Cosmos DB account.
+type Client struct {
+	endpoint string
+	pipeline runtime.Pipeline
+}
+
+// NewClient returns a client that authenticates with Microsoft Entra ID tokens from cred,
+// like azcosmos.NewClient. Pass the same credential and endpoint as for the azcosmos client.
+func NewClient(endpoint string, cred azcore.TokenCredential, opts *azcore.ClientOptions) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	scope := fmt.Sprintf("%s://%s/.default", u.Scheme, u.Hostname())
+	return newClient(endpoint, opts, runtime.NewBearerTokenPolicy(cred, []string{scope}, nil), aadPolicy{})
+}
+
+// NewClientWithKey returns a client that authenticates with an account key, like azcosmos.NewClientWithKey.
+func NewClientWithKey(endpoint, key string, opts *azcore.ClientOptions) (*Client, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("decode account key: %w", err)
+	}
+	return newClient(endpoint, opts, keyPolicy{key: decoded})
+}
+
+func newClient(endpoint string, opts *azcore.ClientOptions, authPolicies ...policy.Policy) (*Client, error) {
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	pipeline := runtime.NewPipeline(moduleName, moduleVersion(), runtime.PipelineOptions{PerRetry: authPolicies}, opts)
+	return &Client{endpoint: strings.TrimSuffix(endpoint, "/"), pipeline: pipeline}, nil
+}
+
+// moduleVersion returns the version of this module in the running binary, which is sent in the User-Agent header.
+func moduleVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, m := range append([]*debug.Module{&info.Main}, info.Deps...) {
+			if m.Path == modulePath && m.Version != "" {
+				return m.Version
+			}
+		}
+	}
+	return "(devel)"
+}
+
+// do sends a request for the resource at the given path segments, e.g. "dbs", "db", "colls", "c", "sprocs".
+// Responses other than 200, 201 and 204 are returned as *azcore.ResponseError.
+func (c *Client) do(ctx context.Context, method string, body any, header http.Header, segments ...string) (*http.Response, error) {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	req, err := runtime.NewRequest(ctx, method, c.endpoint+"/"+strings.Join(escaped, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		if err := runtime.MarshalAsJSON(req, body); err != nil {
+			return nil, err
+		}
+	}
+	req.Raw().Header.Set(headerVersion, apiVersion)
+	for name, values := range header {
+		for _, value := range values {
+			req.Raw().Header.Add(name, value)
+		}
+	}
+
+	resp, err := c.pipeline.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK, http.StatusCreated, http.StatusNoContent) {
+		return nil, runtime.NewResponseError(resp)
+	}
+	return resp, nil
+}
+
+// keyPolicy signs requests with the account key.
+// See https://learn.microsoft.com/rest/api/cosmos-db/access-control-on-cosmosdb-resources
+type keyPolicy struct {
+	key []byte
+}
+
+func (p keyPolicy) Do(req *policy.Request) (*http.Response, error) {
+	date := time.Now().UTC().Format(http.TimeFormat)
+	req.Raw().Header.Set(headerDate, date)
+
+	resourceType, resourceLink := resourceOf(req.Raw().URL.Path)
+	stringToSign := strings.ToLower(req.Raw().Method) + "\n" + resourceType + "\n" + resourceLink + "\n" + strings.ToLower(date) + "\n\n"
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(stringToSign))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	req.Raw().Header.Set(headerAuthorization, url.QueryEscape("type=master&ver=1.0&sig="+signature))
+	return req.Next()
+}
+
+// resourceOf returns the resource type and link that are signed for a request path. For a feed such as
+// /dbs/db/colls/c/sprocs the link is the parent resource, for an item such as /dbs/db/colls/c/sprocs/s the item itself.
+func resourceOf(path string) (resourceType, resourceLink string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments)%2 == 1 {
+		return segments[len(segments)-1], strings.Join(segments[:len(segments)-1], "/")
+	}
+	return segments[len(segments)-2], strings.Join(segments, "/")
+}
+
+// aadPolicy rewrites the bearer token set by runtime.BearerTokenPolicy into the Cosmos DB authorization format.
+type aadPolicy struct{}
+
+func (aadPolicy) Do(req *policy.Request) (*http.Response, error) {
+	req.Raw().Header.Set(headerDate, time.Now().UTC().Format(http.TimeFormat))
+	if token, ok := strings.CutPrefix(req.Raw().Header.Get(headerAuthorization), "Bearer "); ok {
+		req.Raw().Header.Set(headerAuthorization, "type=aad&ver=1.0&sig="+token)
+	}
+	return req.Next()
+}
@@ -0,0 +1,268 @@
+package scripts
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+)
+
+// Kind is the kind of a server-side script. Its value is the resource type in the REST API.
+type Kind string
+
+const (
+	StoredProcedure     Kind = "sprocs"
+	UserDefinedFunction Kind = "udfs"
+	Trigger             Kind = "triggers"
+)
+
+// name returns the name of the kind used in operation names, e.g. "StoredProcedure".
+func (k Kind) name() string {
+	switch k {
+	case StoredProcedure:
+		return "StoredProcedure"
+	case UserDefinedFunction:
+		return "UserDefinedFunction"
+	}
+	return "Trigger"
+}
+
+// TriggerType defines whether a trigger runs before or after the operation.
+type TriggerType string
+
+const (
+	TriggerPre  TriggerType = "Pre"
+	TriggerPost TriggerType = "Post"
+)
+
+// TriggerOperation is the operation a trigger runs for.
+type TriggerOperation string
+
+const (
+	TriggerAll     TriggerOperation = "All"
+	TriggerCreate  TriggerOperation = "Create"
+	TriggerReplace TriggerOperation = "Replace"
+	TriggerDelete  TriggerOperation = "Delete"
+)
+
+// Script is a stored procedure, user-defined function or trigger.
+type Script struct {
+	ID   string `json:"id"`
+	Kind Kind   `json:"-"`
+	Body string `json:"body"`
+	// TriggerType and TriggerOperation are set for triggers only.
+	TriggerType      TriggerType      `json:"triggerType,omitempty"`
+	TriggerOperation TriggerOperation `json:"triggerOperation,omitempty"`
+}
+
+// hash identifies the deployed content of the script.
+func (s Script) hash() [sha256.Size]byte {
+	return sha256.Sum256([]byte(string(s.TriggerType) + "\x00" + string(s.TriggerOperation) + "\x00" + s.Body))
+}
+
+// Deploy creates the script in a container, or replaces it if the hash of its body (and, for triggers,
+// its type and operation) differs from the deployed one. It reports whether anything was changed,
+// so that it can be run on every application start.
+func (c *Client) Deploy(ctx context.Context, database, container string, script Script) (bool, error) {
+	switch script.Kind {
+	case StoredProcedure, UserDefinedFunction:
+	case Trigger:
+		script.TriggerOperation = cmp.Or(script.TriggerOperation, TriggerAll)
+	default:
+		return false, fmt.Errorf("script %q has unknown kind %q", script.ID, script.Kind)
+	}
+	resource := []string{"dbs", database, "colls", container, string(script.Kind)}
+
+	resp, err := c.do(ctx, http.MethodGet, nil, nil, append(resource, script.ID)...)
+	if err != nil {
+		if !errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrNotFound) {
+			return false, scriptError("Read", database, container, script, err)
+		}
+		// Script doesn't exist, try to create it
+		_, err = c.do(ctx, http.MethodPost, script, nil, resource...)
+		if err != nil {
+			if errors.Is(cosmosdb_errors.Wrap(err), cosmosdb_errors.ErrConflict) {
+				// Script was created by another process, treat as success
+				return false, nil
+			}
+			return false, scriptError("Create", database, container, script, err)
+		}
+		return true, nil
+	}
+
+	var deployed Script
+	if err := runtime.UnmarshalAsJSON(resp, &deployed); err != nil {
+		return false, scriptError("Read", database, container, script, err)
+	}
+	if deployed.hash() == script.hash() {
+		return false, nil
+	}
+	if _, err := c.do(ctx, http.MethodPut, script, nil, append(resource, script.ID)...); err != nil {
+		return false, scriptError("Replace", database, container, script, err)
+	}
+	return true, nil
+}
+
+// DeployAll deploys scripts with Deploy and returns the ones that were created or replaced.
+func (c *Client) DeployAll(ctx context.Context, database, container string, scripts []Script) ([]Script, error) {
+	var changed []Script
+	for _, script := range scripts {
+		ok, err := c.Deploy(ctx, database, container, script)
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed = append(changed, script)
+		}
+	}
+	return changed, nil
+}
+
+// DeployDir deploys the scripts in a directory, see LoadFS for its layout, and returns the ones that were created or replaced.
+func (c *Client) DeployDir(ctx context.Context, database, container, dir string) ([]Script, error) {
+	scripts, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return c.DeployAll(ctx, database, container, scripts)
+}
+
+// EnsureStoredProcedure deploys a stored procedure with Deploy. It implements schema.StoredProcedureDeployer.
+func (c *Client) EnsureStoredProcedure(ctx context.Context, database, container, id, body string) (bool, error) {
+	return c.Deploy(ctx, database, container, Script{ID: id, Kind: StoredProcedure, Body: body})
+}
+
+// LoadDir loads the scripts in a directory with LoadFS.
+func LoadDir(dir string) ([]Script, error) {
+	return LoadFS(os.DirFS(dir))
+}
+
+// LoadFS loads scripts from the .js files of a file system, e.g. an embed.FS, laid out as:
+//
+//	sprocs/<id>.js
+//	udfs/<id>.js
+//	triggers/<id>.<pre|post>.js
+//	triggers/<id>.<pre|post>.<all|create|replace|delete>.js
+//
+// Triggers without an operation run for all operations. Other files are ignored.
+func LoadFS(fsys fs.FS) ([]Script, error) {
+	var scripts []Script
+	for _, kind := range []Kind{StoredProcedure, UserDefinedFunction, Trigger} {
+		files, err := fs.Glob(fsys, string(kind)+"/*.js")
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			body, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			script := Script{ID: strings.TrimSuffix(path.Base(file), ".js"), Kind: kind, Body: string(body)}
+			if kind == Trigger {
+				if script, err = parseTriggerName(script); err != nil {
+					return nil, fmt.Errorf("%s: %w", file, err)
+				}
+			}
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts, nil
+}
+
+// parseTriggerName sets the trigger type and operation from the file name of a trigger, e.g. validate.pre.create.
+func parseTriggerName(script Script) (Script, error) {
+	parts := strings.Split(script.ID, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return script, errors.New("trigger file name must be <id>.<pre|post>[.<operation>].js")
+	}
+	script.ID = parts[0]
+
+	types := []TriggerType{TriggerPre, TriggerPost}
+	i := slices.IndexFunc(types, func(t TriggerType) bool { return strings.EqualFold(string(t), parts[1]) })
+	if i < 0 {
+		return script, fmt.Errorf("unknown trigger type %q", parts[1])
+	}
+	script.TriggerType = types[i]
+
+	script.TriggerOperation = TriggerAll
+	if len(parts) == 3 {
+		operations := []TriggerOperation{TriggerAll, TriggerCreate, TriggerReplace, TriggerDelete}
+		i := slices.IndexFunc(operations, func(o TriggerOperation) bool { return strings.EqualFold(string(o), parts[2]) })
+		if i < 0 {
+			return script, fmt.Errorf("unknown trigger operation %q", parts[2])
+		}
+		script.TriggerOperation = operations[i]
+	}
+	return script, nil
+}
+
+// ExecuteStoredProcedure executes a stored procedure in the given partition, passing params as its arguments,
+// and decodes the value the stored procedure sets with getContext().getResponse().setBody() into T.
+// pk holds the values of the partition key in level order, as they appear in the documents, e.g. []any{"customer-42"};
+// values may be strings, numbers, booleans or nil.
+func ExecuteStoredProcedure[T any](c *Client, database, container, id string, pk []any, params ...any) (T, error) {
+	return ExecuteStoredProcedureCtx[T](context.Background(), c, database, container, id, pk, params...)
+}
+
+// ExecuteStoredProcedureCtx is like ExecuteStoredProcedure but uses the provided context for the Cosmos DB call.
+func ExecuteStoredProcedureCtx[T any](ctx context.Context, c *Client, database, container, id string, pk []any, params ...any) (T, error) {
+	var result T
+	fail := func(err error) (T, error) {
+		return result, scriptError("Execute", database, container, Script{ID: id, Kind: StoredProcedure}, err)
+	}
+
+	pkJSON, err := partitionKeyJSON(pk)
+	if err != nil {
+		return fail(err)
+	}
+	if params == nil {
+		params = []any{}
+	}
+	header := http.Header{headerPartitionKey: {pkJSON}}
+	resp, err := c.do(ctx, http.MethodPost, params, header, "dbs", database, "colls", container, string(StoredProcedure), id)
+	if err != nil {
+		return fail(err)
+	}
+	if err := runtime.UnmarshalAsJSON(resp, &result); err != nil {
+		return fail(err)
+	}
+	return result, nil
+}
+
+// partitionKeyJSON returns the JSON array form of the partition key values that is sent in the partition key header.
+func partitionKeyJSON(values []any) (string, error) {
+	if len(values) == 0 {
+		return "", errors.New("partition key has no values")
+	}
+	for _, value := range values {
+		switch value.(type) {
+		case nil, string, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		default:
+			return "", fmt.Errorf("unsupported partition key value of type %T", value)
+		}
+	}
+	b, err := json.Marshal(values)
+	return string(b), err
+}
+
+// scriptError wraps err in a cosmosdb_errors.OperationError, e.g. for the operation "ReplaceStoredProcedure".
+func scriptError(verb, database, container string, script Script, err error) error {
+	return cosmosdb_errors.NewOperationError(cosmosdb_errors.OperationError{
+		Operation: verb + script.Kind.name(),
+		Database:  database,
+		Container: container,
+		ItemID:    script.ID,
+		Err:       err,
+	})
+}
@@ -0,0 +1,157 @@
+package scripts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/cosmosdb_errors"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/internal/cosmostest"
+	"github.com/abhirockzz/cosmosdb-go-sdk-helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ schema.StoredProcedureDeployer = (*Client)(nil)
+
+// scriptStore serves script resources by path and records write requests.
+type scriptStore struct {
+	t       *testing.T
+	mu      sync.Mutex
+	scripts map[string]json.RawMessage
+	writes  []string
+}
+
+func (s *scriptStore) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assert.True(s.t, strings.HasPrefix(r.Header.Get("Authorization"), "type%3Dmaster%26ver%3D1.0%26sig%3D"))
+	assert.NotEmpty(s.t, r.Header.Get("x-ms-date"))
+
+	switch r.Method {
+	case http.MethodGet:
+		body, ok := s.scripts[r.URL.Path]
+		if !ok {
+			cosmostest.WriteError(w, http.StatusNotFound, "NotFound", "not found")
+			return
+		}
+		cosmostest.WriteJSON(w, http.StatusOK, body)
+	case http.MethodPost, http.MethodPut:
+		s.writes = append(s.writes, r.Method+" "+r.URL.Path)
+		var body json.RawMessage
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		var script struct {
+			ID string `json:"id"`
+		}
+		require.NoError(s.t, json.Unmarshal(body, &script))
+		itemPath := r.URL.Path
+		if r.Method == http.MethodPost {
+			itemPath += "/" + script.ID
+		}
+		s.scripts[itemPath] = body
+		cosmostest.WriteJSON(w, http.StatusCreated, body)
+	}
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	opts := cosmostest.ClientOptions(handler)
+	client, err := NewClientWithKey(cosmostest.Endpoint, cosmostest.Key, &opts)
+	require.NoError(t, err)
+	return client
+}
+
+func TestLoadFS(t *testing.T) {
+	scripts, err := LoadFS(fstest.MapFS{
+		"sprocs/bulkDelete.js":            {Data: []byte("function bulkDelete() {}")},
+		"udfs/tax.js":                     {Data: []byte("function tax(x) { return x * 0.2 }")},
+		"triggers/validate.pre.create.js": {Data: []byte("function validate() {}")},
+		"triggers/audit.post.js":          {Data: []byte("function audit() {}")},
+		"README.md":                       {Data: []byte("ignored")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Script{
+		{ID: "bulkDelete", Kind: StoredProcedure, Body: "function bulkDelete() {}"},
+		{ID: "tax", Kind: UserDefinedFunction, Body: "function tax(x) { return x * 0.2 }"},
+		{ID: "audit", Kind: Trigger, Body: "function audit() {}", TriggerType: TriggerPost, TriggerOperation: TriggerAll},
+		{ID: "validate", Kind: Trigger, Body: "function validate() {}", TriggerType: TriggerPre, TriggerOperation: TriggerCreate},
+	}, scripts)
+
+	_, err = LoadFS(fstest.MapFS{"triggers/audit.js": {Data: []byte("function audit() {}")}})
+	assert.EqualError(t, err, "triggers/audit.js: trigger file name must be <id>.<pre|post>[.<operation>].js")
+}
+
+func TestDeployAll(t *testing.T) {
+	store := &scriptStore{t: t, scripts: map[string]json.RawMessage{}}
+	client := newTestClient(t, store.handle)
+	ctx := context.Background()
+	scripts := []Script{
+		{ID: "bulkDelete", Kind: StoredProcedure, Body: "function bulkDelete() {}"},
+		{ID: "audit", Kind: Trigger, Body: "function audit() {}", TriggerType: TriggerPost},
+	}
+
+	changed, err := client.DeployAll(ctx, "db", "container", scripts)
+	require.NoError(t, err)
+	assert.Len(t, changed, 2)
+	assert.JSONEq(t, `{"id":"audit","body":"function audit() {}","triggerType":"Post","triggerOperation":"All"}`,
+		string(store.scripts["/dbs/db/colls/container/triggers/audit"]))
+
+	// unchanged scripts are not written again
+	changed, err = client.DeployAll(ctx, "db", "container", scripts)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	scripts[0].Body = "function bulkDelete() { /* v2 */ }"
+	changed, err = client.DeployAll(ctx, "db", "container", scripts)
+	require.NoError(t, err)
+	assert.Equal(t, []Script{scripts[0]}, changed)
+	assert.Equal(t, []string{
+		"POST /dbs/db/colls/container/sprocs",
+		"POST /dbs/db/colls/container/triggers",
+		"PUT /dbs/db/colls/container/sprocs/bulkDelete",
+	}, store.writes)
+}
+
+func TestExecuteStoredProcedure(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/dbs/db/colls/container/sprocs/countOrders", r.URL.Path)
+		assert.Equal(t, `["c42"]`, r.Header.Get("x-ms-documentdb-partitionkey"))
+		var params []any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		assert.Equal(t, []any{"open", float64(10)}, params)
+		cosmostest.WriteJSON(w, http.StatusOK, map[string]int{"count": 3})
+	})
+
+	result, err := ExecuteStoredProcedure[struct{ Count int }](client, "db", "container", "countOrders",
+		[]any{"c42"}, "open", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Count)
+}
+
+func TestExecuteStoredProcedure_Error(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cosmostest.WriteError(w, http.StatusBadRequest, "BadRequest", "Encountered exception while executing function")
+	})
+
+	_, err := ExecuteStoredProcedureCtx[any](context.Background(), client, "db", "container", "fail", []any{"c42"})
+	var opErr *cosmosdb_errors.OperationError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "ExecuteStoredProcedure", opErr.Operation)
+	assert.Equal(t, "fail", opErr.ItemID)
+	assert.Equal(t, http.StatusBadRequest, cosmosdb_errors.GetError(err).Status)
+}
+
+func TestPartitionKeyJSON(t *testing.T) {
+	s, err := partitionKeyJSON([]any{"tenant", 7, true, nil})
+	require.NoError(t, err)
+	assert.Equal(t, `["tenant",7,true,null]`, s)
+
+	_, err = partitionKeyJSON(nil)
+	assert.Error(t, err)
+	_, err = partitionKeyJSON([]any{map[string]any{"a": 1}})
+	assert.ErrorContains(t, err, "unsupported partition key value")
+}